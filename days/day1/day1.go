@@ -1,39 +1,20 @@
 package day1
 
 import (
+	"flag"
 	"fmt"
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
+	"robertbrignull/adventofcode2023/shared/ahocorasick"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 func isDigit(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
 
-func decodeNumber(str string) int {
-	if len(str) >= 3 && str[0:3] == "one" {
-		return 1
-	} else if len(str) >= 3 && str[0:3] == "two" {
-		return 2
-	} else if len(str) >= 5 && str[0:5] == "three" {
-		return 3
-	} else if len(str) >= 4 && str[0:4] == "four" {
-		return 4
-	} else if len(str) >= 4 && str[0:4] == "five" {
-		return 5
-	} else if len(str) >= 3 && str[0:3] == "six" {
-		return 6
-	} else if len(str) >= 5 && str[0:5] == "seven" {
-		return 7
-	} else if len(str) >= 5 && str[0:5] == "eight" {
-		return 8
-	} else if len(str) >= 4 && str[0:4] == "nine" {
-		return 9
-	} else {
-		return 0
-	}
-}
-
 func firstDigit(line string) (int, error) {
 	for i := range line {
 		if isDigit(line[i]) {
@@ -43,43 +24,183 @@ func firstDigit(line string) (int, error) {
 	return 0, fmt.Errorf("string does not contain any digits: %s", line)
 }
 
-func firstNumber(line string) (int, error) {
-	for i := range line {
+func lastDigit(line string) (int, error) {
+	for j := range line {
+		i := len(line) - 1 - j
 		if isDigit(line[i]) {
 			return strconv.Atoi(line[i : i+1])
 		}
+	}
+	return 0, fmt.Errorf("string does not contain any digits: %s", line)
+}
+
+// A word that stands for a number. Calibration values are made from the
+// first and last digits of a line, so a token worth more than 9 counts as its
+// first digit when it is the first match and its last digit when it is the
+// last match, as though the number had been written out in digits.
+type Token struct {
+	word  string
+	value int
+}
+
+type Vocabulary []Token
+
+var vocabularies = map[string]Vocabulary{
+	"english": {
+		{"one", 1}, {"two", 2}, {"three", 3}, {"four", 4}, {"five", 5},
+		{"six", 6}, {"seven", 7}, {"eight", 8}, {"nine", 9},
+	},
+	"english-extended": {
+		{"zero", 0}, {"one", 1}, {"two", 2}, {"three", 3}, {"four", 4}, {"five", 5},
+		{"six", 6}, {"seven", 7}, {"eight", 8}, {"nine", 9}, {"ten", 10},
+	},
+	"german": {
+		{"eins", 1}, {"zwei", 2}, {"drei", 3}, {"vier", 4}, {"fünf", 5},
+		{"sechs", 6}, {"sieben", 7}, {"acht", 8}, {"neun", 9},
+	},
+	"french": {
+		{"un", 1}, {"deux", 2}, {"trois", 3}, {"quatre", 4}, {"cinq", 5},
+		{"six", 6}, {"sept", 7}, {"huit", 8}, {"neuf", 9},
+	},
+	"spanish": {
+		{"uno", 1}, {"dos", 2}, {"tres", 3}, {"cuatro", 4}, {"cinco", 5},
+		{"seis", 6}, {"siete", 7}, {"ocho", 8}, {"nueve", 9},
+	},
+	"none": {},
+}
 
-		n := decodeNumber(line[i:])
-		if n != 0 {
-			return n, nil
+func vocabularyNames() []string {
+	names := make([]string, 0, len(vocabularies))
+	for name := range vocabularies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func digitVocabulary() Vocabulary {
+	v := Vocabulary{}
+	for d := 0; d <= 9; d++ {
+		v = append(v, Token{strconv.Itoa(d), d})
+	}
+	return v
+}
+
+// Reads a vocabulary from a file containing one "<word> <value>" pair per line
+func readVocabularyFile(filename string) (Vocabulary, error) {
+	lines, err := shared.ReadFileLines(filename)
+	if err != nil {
+		return Vocabulary{}, err
+	}
+
+	v := Vocabulary{}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
 		}
+		var t Token
+		_, err := fmt.Sscanf(line, "%s %d", &t.word, &t.value)
+		if err != nil {
+			return Vocabulary{}, fmt.Errorf("invalid vocabulary line '%s': %s", line, err)
+		}
+		if t.value < 0 {
+			return Vocabulary{}, fmt.Errorf("invalid vocabulary line '%s': value must not be negative", line)
+		}
+		v = append(v, t)
 	}
-	return 0, fmt.Errorf("string does not contain any digits: %s", line)
+	return v, nil
 }
 
-func lastDigit(line string) (int, error) {
-	for j := range line {
-		i := len(line) - 1 - j
-		if isDigit(line[i]) {
-			return strconv.Atoi(line[i : i+1])
+// Finds the first and last tokens of a vocabulary in a line of text. Digits
+// are always recognised in addition to the words of the vocabulary.
+type NumberMatcher struct {
+	tokens  Vocabulary
+	matcher *ahocorasick.Matcher
+}
+
+func newNumberMatcher(vocabulary Vocabulary) NumberMatcher {
+	tokens := append(digitVocabulary(), vocabulary...)
+	words := make([]string, len(tokens))
+	for i, t := range tokens {
+		words[i] = t.word
+	}
+	return NumberMatcher{tokens, ahocorasick.New(words)}
+}
+
+// Returns the matches that start first and last in the line. If several
+// tokens start at the same position then the longest one is used.
+func (nm NumberMatcher) firstAndLast(line string) (ahocorasick.Match, ahocorasick.Match, error) {
+	var first, last ahocorasick.Match
+	found := false
+	nm.matcher.Scan(line, func(m ahocorasick.Match) {
+		if !found || m.Start < first.Start || (m.Start == first.Start && m.End > first.End) {
+			first = m
+		}
+		if !found || m.Start > last.Start || (m.Start == last.Start && m.End > last.End) {
+			last = m
 		}
+		found = true
+	})
+	if !found {
+		return first, last, fmt.Errorf("string does not contain any numbers: %s", line)
 	}
-	return 0, fmt.Errorf("string does not contain any digits: %s", line)
+	return first, last, nil
 }
 
-func lastNumber(line string) (int, error) {
-	for j := range line {
-		i := len(line) - 1 - j
-		if isDigit(line[i]) {
-			return strconv.Atoi(line[i : i+1])
+func (nm NumberMatcher) value(m ahocorasick.Match) int {
+	return nm.tokens[m.Pattern].value
+}
+
+// Returns the digit a match contributes when it is the first match in a line
+func (nm NumberMatcher) firstDigit(m ahocorasick.Match) int {
+	v := nm.value(m)
+	for v > 9 {
+		v /= 10
+	}
+	return v
+}
+
+// Returns the digit a match contributes when it is the last match in a line
+func (nm NumberMatcher) lastDigit(m ahocorasick.Match) int {
+	return nm.value(m) % 10
+}
+
+// Combines the first and last matches of a line into its calibration value
+func (nm NumberMatcher) calibrationValue(first ahocorasick.Match, last ahocorasick.Match) int {
+	return nm.firstDigit(first)*10 + nm.lastDigit(last)
+}
+
+type vocabularyFlags struct {
+	names *string
+	file  *string
+}
+
+func addVocabularyFlags(flags *flag.FlagSet) vocabularyFlags {
+	return vocabularyFlags{
+		names: flags.String("vocab", "english", "comma separated vocabularies of number words to recognise: "+strings.Join(vocabularyNames(), ", ")),
+		file:  flags.String("vocab-file", "", "file of extra \"<word> <value>\" tokens to recognise"),
+	}
+}
+
+func (vf vocabularyFlags) buildMatcher() (NumberMatcher, error) {
+	vocabulary := Vocabulary{}
+	for _, name := range strings.Split(*vf.names, ",") {
+		v, ok := vocabularies[name]
+		if !ok {
+			return NumberMatcher{}, fmt.Errorf("Unrecognised vocabulary: %s", name)
 		}
+		vocabulary = append(vocabulary, v...)
+	}
 
-		n := decodeNumber(line[i:])
-		if n != 0 {
-			return n, nil
+	if *vf.file != "" {
+		v, err := readVocabularyFile(*vf.file)
+		if err != nil {
+			return NumberMatcher{}, err
 		}
+		vocabulary = append(vocabulary, v...)
 	}
-	return 0, fmt.Errorf("string does not contain any digits: %s", line)
+
+	return newNumberMatcher(vocabulary), nil
 }
 
 // Time taken: 30 minutes
@@ -89,45 +210,165 @@ func Part1() (string, error) {
 		return "", err
 	}
 
-	sum := 0
-	for _, line := range lines {
+	sum, err := shared.ParallelSum(lines, func(line string) (int, error) {
 		f, err := firstDigit(line)
 		if err != nil {
-			return "", err
+			return 0, err
 		}
 
 		l, err := lastDigit(line)
 		if err != nil {
-			return "", err
+			return 0, err
 		}
 
-		sum += f*10 + l
+		return f*10 + l, nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	return strconv.Itoa(sum), nil
 }
 
 // Time taken: 11 minutes
-func Part2() (string, error) {
+func Part2(args []string) (string, error) {
+	flags := flag.NewFlagSet("day1 part2", flag.ContinueOnError)
+	vf := addVocabularyFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	nm, err := vf.buildMatcher()
+	if err != nil {
+		return "", err
+	}
+
 	lines, err := shared.ReadFileLines("days/day1/input.txt")
 	if err != nil {
 		return "", err
 	}
 
-	sum := 0
-	for _, line := range lines {
-		f, err := firstNumber(line)
+	sum, err := shared.ParallelSum(lines, func(line string) (int, error) {
+		f, l, err := nm.firstAndLast(line)
 		if err != nil {
-			return "", err
+			return 0, err
 		}
 
-		l, err := lastNumber(line)
+		return nm.calibrationValue(f, l), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(sum), nil
+}
+
+const (
+	ansiReset = "\033[0m"
+	ansiFirst = "\033[1;32m"
+	ansiLast  = "\033[1;36m"
+	ansiBoth  = "\033[1;33m"
+)
+
+// Returns the line with the first and last matches highlighted. Characters
+// covered by both matches, as in "eightwo", get their own colour.
+func highlightMatches(line string, first ahocorasick.Match, last ahocorasick.Match) string {
+	var sb strings.Builder
+	currentColour := ""
+	for i := 0; i < len(line); i++ {
+		inFirst := i >= first.Start && i < first.End
+		inLast := i >= last.Start && i < last.End
+
+		colour := ""
+		if inFirst && inLast {
+			colour = ansiBoth
+		} else if inFirst {
+			colour = ansiFirst
+		} else if inLast {
+			colour = ansiLast
+		}
+
+		if colour != currentColour {
+			if currentColour != "" {
+				sb.WriteString(ansiReset)
+			}
+			sb.WriteString(colour)
+			currentColour = colour
+		}
+		sb.WriteByte(line[i])
+	}
+	if currentColour != "" {
+		sb.WriteString(ansiReset)
+	}
+	return sb.String()
+}
+
+// Shows how the calibration value of every line is derived. Use --vocab none
+// to explain part 1, where only digits are recognised.
+func Explain(args []string) (string, error) {
+	flags := flag.NewFlagSet("day1 explain", flag.ContinueOnError)
+	vf := addVocabularyFlags(flags)
+	colour := flags.Bool("color", false, "highlight the matched tokens using ANSI colours")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	nm, err := vf.buildMatcher()
+	if err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day1/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sum := 0
+	for i, line := range lines {
+		f, l, err := nm.firstAndLast(line)
 		if err != nil {
-			return "", err
+			return "", fmt.Errorf("line %d: %s", i+1, err)
+		}
+
+		value := nm.calibrationValue(f, l)
+		sum += value
+
+		shownLine := line
+		if *colour {
+			shownLine = highlightMatches(line, f, l)
 		}
 
-		sum += f*10 + l
+		fmt.Fprintf(&sb, "%d: %s\n", i+1, shownLine)
+		fmt.Fprintf(&sb, "    first: %q at %d-%d = %d\n", line[f.Start:f.End], f.Start, f.End-1, nm.firstDigit(f))
+		fmt.Fprintf(&sb, "    last:  %q at %d-%d = %d\n", line[l.Start:l.End], l.Start, l.End-1, nm.lastDigit(l))
+		fmt.Fprintf(&sb, "    value: %d, running sum: %d\n", value, sum)
 	}
+	fmt.Fprintf(&sb, "total: %d", sum)
 
-	return strconv.Itoa(sum), nil
+	return sb.String(), nil
+}
+
+// Generates lines of letters mixed with digits and English number words.
+// Every line contains at least one digit so that both parts can be solved.
+func Generate(r *rand.Rand, size int) []string {
+	words := vocabularies["english"]
+	lines := make([]string, size)
+	for i := range lines {
+		var sb strings.Builder
+		numTokens := 1 + r.Intn(6)
+		digitToken := r.Intn(numTokens)
+		for t := 0; t < numTokens; t++ {
+			for j := r.Intn(5); j > 0; j-- {
+				sb.WriteByte(byte('a' + r.Intn(26)))
+			}
+			if t == digitToken || r.Intn(2) == 0 {
+				sb.WriteByte(byte('1' + r.Intn(9)))
+			} else {
+				sb.WriteString(words[r.Intn(len(words))].word)
+			}
+		}
+		lines[i] = sb.String()
+	}
+	return lines
 }
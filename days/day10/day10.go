@@ -1,8 +1,12 @@
 package day10
 
 import (
+	"flag"
 	"fmt"
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
+	"robertbrignull/adventofcode2023/shared/containers"
+	"robertbrignull/adventofcode2023/shared/polygon"
 	"strconv"
 )
 
@@ -212,7 +216,7 @@ func (pf PipeField) determineStartPipe(c Coord) (Pipe, error) {
 		return NS, nil
 	} else if !connectsN && connectsE && !connectsS && connectsW {
 		return EW, nil
-	} else if connectsN && connectsE && !connectsS && connectsW {
+	} else if connectsN && connectsE && !connectsS && !connectsW {
 		return NE, nil
 	} else if !connectsN && connectsE && connectsS && !connectsW {
 		return ES, nil
@@ -288,21 +292,19 @@ func (pf PipeField) cleanTilesNotOnLoop(start Coord) error {
 }
 
 func (pf PipeField) isCoordInsideLoop(start Coord, loopTiles map[Coord]bool) (bool, int, error) {
-	coordsToProcess := make([]Coord, 1)
-	coordsToProcess[0] = start
+	coordsToProcess := containers.NewDeque[Coord]()
+	coordsToProcess.PushBack(start)
 
 	coordsSeen := make(map[Coord]bool)
 
 	tilesContained := make(map[Coord]bool)
 
 	for {
-		if len(coordsToProcess) == 0 {
+		c, ok := coordsToProcess.PopFront()
+		if !ok {
 			return true, len(tilesContained), nil
 		}
 
-		c := coordsToProcess[0]
-		coordsToProcess = coordsToProcess[1:]
-
 		if _, ok := coordsSeen[c]; ok {
 			continue
 		}
@@ -317,16 +319,16 @@ func (pf PipeField) isCoordInsideLoop(start Coord, loopTiles map[Coord]bool) (bo
 		}
 
 		if c.y > 0 && !pf.getPipe(c.moveDirection(N)).isMovementIntoTileBlocked(N) {
-			coordsToProcess = append(coordsToProcess, c.moveDirection(N))
+			coordsToProcess.PushBack(c.moveDirection(N))
 		}
 		if !pf.getPipe(c).isMovementOutofTileBlocked(E) {
-			coordsToProcess = append(coordsToProcess, c.moveDirection(E))
+			coordsToProcess.PushBack(c.moveDirection(E))
 		}
 		if !pf.getPipe(c).isMovementOutofTileBlocked(S) {
-			coordsToProcess = append(coordsToProcess, c.moveDirection(S))
+			coordsToProcess.PushBack(c.moveDirection(S))
 		}
 		if c.x > 0 && !pf.getPipe(c.moveDirection(W)).isMovementIntoTileBlocked(W) {
-			coordsToProcess = append(coordsToProcess, c.moveDirection(W))
+			coordsToProcess.PushBack(c.moveDirection(W))
 		}
 	}
 }
@@ -361,6 +363,52 @@ func (pf PipeField) findAreaEnclosedByPipeLoop(start Coord) (int, error) {
 	}
 }
 
+func (p Pipe) isCorner() bool {
+	return p == NE || p == ES || p == SW || p == WN
+}
+
+// Returns the corners of the loop in the order they are visited
+func (pf PipeField) findLoopVertices(start Coord) ([]polygon.Point, error) {
+	vertices := []polygon.Point{}
+
+	c := start
+
+	prevDirection, err := pf.getPipe(start).getConnectingDirection()
+	if err != nil {
+		return []polygon.Point{}, err
+	}
+
+	for {
+		p := pf.getPipe(c)
+		if p.isCorner() {
+			vertices = append(vertices, polygon.Point{X: c.x, Y: c.y})
+		}
+
+		for _, d := range allDirections() {
+			if p.connectsFrom(d) && prevDirection != d {
+				c = c.moveDirection(d)
+				prevDirection = d.opposite()
+				break
+			}
+		}
+
+		if c == start {
+			return vertices, nil
+		}
+	}
+}
+
+// Computes the same result as findAreaEnclosedByPipeLoop, but by treating the
+// loop as a polygon and counting the lattice points inside it using Pick's theorem
+func (pf PipeField) findAreaEnclosedByPipeLoopUsingPolygon(start Coord) (int, error) {
+	vertices, err := pf.findLoopVertices(start)
+	if err != nil {
+		return 0, err
+	}
+
+	return polygon.InteriorPoints(vertices), nil
+}
+
 // Time taken: 39 minutes
 func Part1() (string, error) {
 	lines, err := shared.ReadFileLines("days/day10/input.txt")
@@ -394,7 +442,13 @@ func Part1() (string, error) {
 }
 
 // Time taken: 59 minutes
-func Part2() (string, error) {
+func Part2(args []string) (string, error) {
+	flags := flag.NewFlagSet("day10 part2", flag.ContinueOnError)
+	method := flags.String("method", "flood", "how to compute the enclosed area: flood or polygon")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
 	lines, err := shared.ReadFileLines("days/day10/input.txt")
 	if err != nil {
 		return "", err
@@ -415,15 +469,173 @@ func Part2() (string, error) {
 		return "", err
 	}
 
-	err = pipeField.cleanTilesNotOnLoop(start)
-	if err != nil {
-		return "", err
-	}
+	var areaEnclosed int
+	if *method == "flood" {
+		err = pipeField.cleanTilesNotOnLoop(start)
+		if err != nil {
+			return "", err
+		}
 
-	areaEnclosed, err := pipeField.findAreaEnclosedByPipeLoop(start)
-	if err != nil {
-		return "", err
+		areaEnclosed, err = pipeField.findAreaEnclosedByPipeLoop(start)
+		if err != nil {
+			return "", err
+		}
+	} else if *method == "polygon" {
+		areaEnclosed, err = pipeField.findAreaEnclosedByPipeLoopUsingPolygon(start)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		return "", fmt.Errorf("Unrecognised method: %s", *method)
 	}
 
 	return strconv.Itoa(areaEnclosed), nil
 }
+
+// Generates a size by size field containing a single loop. The loop is the
+// outline of a random tree on a grid of nodes four tiles apart, which keeps
+// the outline from ever touching itself. The start is placed on a random
+// tile of the loop and all other tiles are filled with random pipes.
+func Generate(r *rand.Rand, size int) ([]string, error) {
+	size = max(size, 3)
+	// The last node is at most size-2, so its outline stays on the grid
+	m := (size-3)/4 + 1
+
+	inTree := make([][]bool, size)
+	for y := range inTree {
+		inTree[y] = make([]bool, size)
+	}
+	nodeCoord := func(n int) Coord {
+		return Coord{4*(n%m) + 1, 4*(n/m) + 1}
+	}
+
+	// Grow a random tree from a random node until it covers between half and all of the nodes
+	type edge struct{ from, to int }
+	frontier := []edge{}
+	treeNodes := make(map[int]bool)
+	addNode := func(n int) {
+		treeNodes[n] = true
+		i, j := n%m, n/m
+		if i > 0 {
+			frontier = append(frontier, edge{n, n - 1})
+		}
+		if i+1 < m {
+			frontier = append(frontier, edge{n, n + 1})
+		}
+		if j > 0 {
+			frontier = append(frontier, edge{n, n - m})
+		}
+		if j+1 < m {
+			frontier = append(frontier, edge{n, n + m})
+		}
+	}
+
+	root := r.Intn(m * m)
+	rootCoord := nodeCoord(root)
+	inTree[rootCoord.y][rootCoord.x] = true
+	addNode(root)
+
+	targetNodes := (m*m+1)/2 + r.Intn(m*m/2+1)
+	for len(treeNodes) < targetNodes {
+		k := r.Intn(len(frontier))
+		e := frontier[k]
+		frontier[k] = frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+		if treeNodes[e.to] {
+			continue
+		}
+
+		a, b := nodeCoord(e.from), nodeCoord(e.to)
+		for y := min(a.y, b.y); y <= max(a.y, b.y); y++ {
+			for x := min(a.x, b.x); x <= max(a.x, b.x); x++ {
+				inTree[y][x] = true
+			}
+		}
+		addNode(e.to)
+	}
+
+	// The loop is every tile that touches the tree, including diagonally
+	onLoop := make(map[Coord]bool)
+	for y := range inTree {
+		for x := range inTree[y] {
+			if inTree[y][x] {
+				continue
+			}
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					ny, nx := y+dy, x+dx
+					if ny >= 0 && ny < size && nx >= 0 && nx < size && inTree[ny][nx] {
+						onLoop[Coord{x, y}] = true
+					}
+				}
+			}
+		}
+	}
+
+	grid := make([][]byte, size)
+	for y := range grid {
+		grid[y] = make([]byte, size)
+		for x := range grid[y] {
+			grid[y][x] = ".......|-LJ7F"[r.Intn(13)]
+		}
+	}
+
+	// Walk around the loop, drawing the pipe that connects each tile to its neighbours
+	pipeChars := map[[2]Direction]byte{
+		{N, S}: '|', {E, W}: '-', {N, E}: 'L', {N, W}: 'J', {S, W}: '7', {S, E}: 'F',
+	}
+	first := Coord{0, 0}
+	for !onLoop[first] {
+		first.x++
+		if first.x == size {
+			first = Coord{0, first.y + 1}
+		}
+	}
+	prev, c := first, first
+	for {
+		connections := []Direction{}
+		var next Coord
+		for _, d := range allDirections() {
+			n := c.moveDirection(d)
+			if onLoop[n] {
+				connections = append(connections, d)
+				if n != prev {
+					next = n
+				}
+			}
+		}
+		if len(connections) != 2 {
+			return nil, fmt.Errorf("loop tile %v has %d neighbours on the loop", c, len(connections))
+		}
+		grid[c.y][c.x] = pipeChars[[2]Direction{connections[0], connections[1]}]
+		prev, c = c, next
+		if c == first {
+			break
+		}
+	}
+
+	// Put the start somewhere on the loop, making sure that no other pipe
+	// next to it looks like it connects to it
+	loopTiles := make([]Coord, 0, len(onLoop))
+	for y := range grid {
+		for x := range grid[y] {
+			if onLoop[Coord{x, y}] {
+				loopTiles = append(loopTiles, Coord{x, y})
+			}
+		}
+	}
+	start := loopTiles[r.Intn(len(loopTiles))]
+	grid[start.y][start.x] = 'S'
+	for _, d := range allDirections() {
+		n := start.moveDirection(d)
+		if n.x >= 0 && n.x < size && n.y >= 0 && n.y < size && !onLoop[n] {
+			grid[n.y][n.x] = '.'
+		}
+	}
+
+	lines := make([]string, size)
+	for y := range grid {
+		lines[y] = string(grid[y])
+	}
+	return lines, nil
+}
@@ -1,8 +1,10 @@
 package day11
 
 import (
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
 	"strconv"
+	"strings"
 )
 
 type Coord struct {
@@ -149,3 +151,20 @@ func Part2() (string, error) {
 
 	return strconv.Itoa(totalDistance), nil
 }
+
+// Generates a size by size image with a galaxy in about one in fifty cells
+func Generate(r *rand.Rand, size int) []string {
+	lines := make([]string, size)
+	for y := range lines {
+		var sb strings.Builder
+		for x := 0; x < size; x++ {
+			if r.Intn(50) == 0 {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		lines[y] = sb.String()
+	}
+	return lines
+}
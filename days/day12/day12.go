@@ -2,7 +2,9 @@ package day12
 
 import (
 	"fmt"
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
+	"robertbrignull/adventofcode2023/shared/memo"
 	"strconv"
 	"strings"
 )
@@ -113,7 +115,36 @@ func readSpringRows(lines []string) ([]SpringRow, error) {
 	return springRows, nil
 }
 
+type combinationsArgs struct {
+	row    SpringRow
+	print  bool
+	indent string
+}
+
+func (r SpringRow) key() string {
+	return printSprings(r.springs) + " " + printBrokenGroups(r.brokenGroups)
+}
+
 func (r SpringRow) countPossibleCombinations(print bool, indent string) int {
+	// A cached result would skip printing its part of the trace, so only
+	// use the memo when not printing
+	if print {
+		var recurse func(combinationsArgs) int
+		recurse = func(a combinationsArgs) int {
+			return countPossibleCombinations(recurse, a)
+		}
+		return recurse(combinationsArgs{r, print, indent})
+	}
+
+	m := memo.NewKeyed(func(a combinationsArgs) string {
+		return a.row.key()
+	}, countPossibleCombinations)
+	return m.Call(combinationsArgs{r, print, indent})
+}
+
+func countPossibleCombinations(recurse func(combinationsArgs) int, a combinationsArgs) int {
+	r, print, indent := a.row, a.print, a.indent
+
 	numWorking := 0
 	for _, s := range r.springs {
 		if s == Working {
@@ -127,7 +158,7 @@ func (r SpringRow) countPossibleCombinations(print bool, indent string) int {
 			springs:      r.springs[numWorking:],
 			brokenGroups: r.brokenGroups,
 		}
-		v := r2.countPossibleCombinations(print, indent+"  ")
+		v := recurse(combinationsArgs{r2, print, indent + "  "})
 		if print {
 			fmt.Printf("%s'%s' '%s' => %d\n", indent, printSprings(r.springs), printBrokenGroups(r.brokenGroups), v)
 		}
@@ -180,7 +211,7 @@ func (r SpringRow) countPossibleCombinations(print bool, indent string) int {
 				springs:      r.springs[group:],
 				brokenGroups: r.brokenGroups[1:],
 			}
-			v := r2.countPossibleCombinations(print, indent+"  ")
+			v := recurse(combinationsArgs{r2, print, indent + "  "})
 			if print {
 				fmt.Printf("%s'%s' '%s' => %d\n", indent, printSprings(r.springs), printBrokenGroups(r.brokenGroups), v)
 			}
@@ -205,7 +236,7 @@ func (r SpringRow) countPossibleCombinations(print bool, indent string) int {
 			springs:      r.springs[group+1:],
 			brokenGroups: r.brokenGroups[1:],
 		}
-		v := r2.countPossibleCombinations(print, indent+"  ")
+		v := recurse(combinationsArgs{r2, print, indent + "  "})
 		if print {
 			fmt.Printf("%s'%s' '%s' => %d\n", indent, printSprings(r.springs), printBrokenGroups(r.brokenGroups), v)
 		}
@@ -220,7 +251,7 @@ func (r SpringRow) countPossibleCombinations(print bool, indent string) int {
 		springs:      copySprings(r.springs, Broken, 0),
 		brokenGroups: r.brokenGroups,
 	}
-	v := r2.countPossibleCombinations(print, indent+"W ") + r3.countPossibleCombinations(print, indent+"B ")
+	v := recurse(combinationsArgs{r2, print, indent + "W "}) + recurse(combinationsArgs{r3, print, indent + "B "})
 	if print {
 		fmt.Printf("%s'%s' '%s' => %d\n", indent, printSprings(r.springs), printBrokenGroups(r.brokenGroups), v)
 	}
@@ -248,3 +279,71 @@ func Part1() (string, error) {
 
 	return strconv.Itoa(total), nil
 }
+
+func (r SpringRow) unfold(copies int) SpringRow {
+	springs := []Spring{}
+	brokenGroups := []int{}
+	for i := 0; i < copies; i++ {
+		if i > 0 {
+			springs = append(springs, Unknown)
+		}
+		springs = append(springs, r.springs...)
+		brokenGroups = append(brokenGroups, r.brokenGroups...)
+	}
+	return SpringRow{springs, brokenGroups}
+}
+
+func Part2() (string, error) {
+	lines, err := shared.ReadFileLines("days/day12/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	springRows, err := readSpringRows(lines)
+	if err != nil {
+		return "", err
+	}
+
+	total := 0
+	for _, r := range springRows {
+		total += r.unfold(5).countPossibleCombinations(false, "")
+	}
+
+	return strconv.Itoa(total), nil
+}
+
+// Generates size rows by choosing a random arrangement of springs and then
+// hiding some of them, so every row has at least one valid arrangement
+func Generate(r *rand.Rand, size int) []string {
+	lines := make([]string, size)
+	for i := range lines {
+		springs := []Spring{}
+		brokenGroups := []int{}
+		for g := 1 + r.Intn(6); g > 0; g-- {
+			// Groups after the first must be separated by at least one working spring
+			gap := r.Intn(3)
+			if len(springs) > 0 {
+				gap = 1 + r.Intn(2)
+			}
+			for j := 0; j < gap; j++ {
+				springs = append(springs, Working)
+			}
+			group := 1 + r.Intn(5)
+			for j := 0; j < group; j++ {
+				springs = append(springs, Broken)
+			}
+			brokenGroups = append(brokenGroups, group)
+		}
+		for j := r.Intn(3); j > 0; j-- {
+			springs = append(springs, Working)
+		}
+
+		for j := range springs {
+			if r.Intn(2) == 0 {
+				springs[j] = Unknown
+			}
+		}
+		lines[i] = printSprings(springs) + " " + printBrokenGroups(brokenGroups)
+	}
+	return lines
+}
@@ -1,147 +1,321 @@
 package day2
 
 import (
+	"encoding/csv"
+	"flag"
 	"fmt"
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
+	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 )
 
-type hand struct {
-	red   int
-	green int
-	blue  int
-}
+// Maps each colour to a number of cubes
+type hand map[string]int
 
 type game struct {
 	id    int
 	hands []hand
 }
 
-func extractHand(line string) (hand, error) {
-	commaParts := strings.Split(line, ", ")
+// A parse error at a particular column of a game record, numbered from 1
+type ParseError struct {
+	Column  int
+	Message string
+}
 
-	h := hand{}
-	for _, commaPart := range commaParts {
-		spaceParts := strings.Split(commaPart, " ")
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Message)
+}
 
-		n, err := strconv.Atoi(spaceParts[0])
-		if err != nil {
-			return hand{}, err
-		}
+type tokenKind int
+
+const (
+	numberToken tokenKind = iota
+	wordToken
+	colonToken
+	semicolonToken
+	commaToken
+	endToken
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case numberToken:
+		return "number"
+	case wordToken:
+		return "word"
+	case colonToken:
+		return "':'"
+	case semicolonToken:
+		return "';'"
+	case commaToken:
+		return "','"
+	case endToken:
+		return "end of line"
+	default:
+		return fmt.Sprintf("%d", int(k))
+	}
+}
 
-		if spaceParts[1] == "red" {
-			h.red = n
-		} else if spaceParts[1] == "green" {
-			h.green = n
-		} else if spaceParts[1] == "blue" {
-			h.blue = n
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func tokenize(line string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(line) {
+		c := line[i]
+		start := i
+		if isSpace(c) {
+			i++
+			continue
+		} else if isDigit(c) {
+			for i < len(line) && isDigit(line[i]) {
+				i++
+			}
+			tokens = append(tokens, token{numberToken, line[start:i], start})
+		} else if isLetter(c) {
+			for i < len(line) && isLetter(line[i]) {
+				i++
+			}
+			tokens = append(tokens, token{wordToken, line[start:i], start})
+		} else if c == ':' {
+			i++
+			tokens = append(tokens, token{colonToken, ":", start})
+		} else if c == ';' {
+			i++
+			tokens = append(tokens, token{semicolonToken, ";", start})
+		} else if c == ',' {
+			i++
+			tokens = append(tokens, token{commaToken, ",", start})
 		} else {
-			return hand{}, fmt.Errorf("Unrecognised colour: %s", spaceParts[1])
+			return []token{}, &ParseError{start + 1, fmt.Sprintf("unexpected character %q", c)}
 		}
 	}
+	return append(tokens, token{endToken, "", len(line)}), nil
+}
 
-	return h, nil
+// A recursive descent parser for the grammar:
+//
+//	game  = "Game" number ":" draw { ";" draw }
+//	draw  = cubes { "," cubes }
+//	cubes = number colour
+//
+// Whitespace is optional between any two tokens.
+type gameParser struct {
+	tokens []token
+	index  int
 }
 
-func extractHands(line string) ([]hand, error) {
-	parts := strings.Split(line, "; ")
+func (p *gameParser) peek() token {
+	return p.tokens[p.index]
+}
 
-	var hands []hand
-	for _, part := range parts {
-		h, err := extractHand(part)
-		if err != nil {
-			return []hand{}, err
+func (p *gameParser) expect(kind tokenKind) (token, error) {
+	t := p.peek()
+	if t.kind != kind {
+		found := t.kind.String()
+		if t.kind != endToken {
+			found = fmt.Sprintf("%q", t.text)
 		}
-		hands = append(hands, h)
+		return token{}, &ParseError{t.pos + 1, fmt.Sprintf("expected %s but found %s", kind, found)}
 	}
+	p.index++
+	return t, nil
+}
 
-	return hands, nil
+func (p *gameParser) parseNumber() (int, error) {
+	t, err := p.expect(numberToken)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(t.text)
+	if err != nil {
+		return 0, &ParseError{t.pos + 1, fmt.Sprintf("invalid number %q", t.text)}
+	}
+	return n, nil
 }
 
-func extractGameInfo(line string) (game, error) {
-	parts := strings.Split(line, ": ")
+func (p *gameParser) parseDraw() (hand, error) {
+	h := hand{}
+	for {
+		n, err := p.parseNumber()
+		if err != nil {
+			return hand{}, err
+		}
+
+		colour, err := p.expect(wordToken)
+		if err != nil {
+			return hand{}, err
+		}
+		if _, ok := h[colour.text]; ok {
+			return hand{}, &ParseError{colour.pos + 1, fmt.Sprintf("colour %s appears more than once in the same draw", colour.text)}
+		}
+		h[colour.text] = n
+
+		if p.peek().kind != commaToken {
+			return h, nil
+		}
+		p.index++
+	}
+}
 
-	id, err := strconv.Atoi(parts[0][5:])
+func (p *gameParser) parseGame() (game, error) {
+	keyword, err := p.expect(wordToken)
 	if err != nil {
 		return game{}, err
 	}
+	if keyword.text != "Game" {
+		return game{}, &ParseError{keyword.pos + 1, fmt.Sprintf("expected \"Game\" but found %q", keyword.text)}
+	}
 
-	hands, err := extractHands(parts[1])
+	id, err := p.parseNumber()
 	if err != nil {
 		return game{}, err
 	}
 
+	if _, err := p.expect(colonToken); err != nil {
+		return game{}, err
+	}
+
+	hands := []hand{}
+	for {
+		h, err := p.parseDraw()
+		if err != nil {
+			return game{}, err
+		}
+		hands = append(hands, h)
+
+		if p.peek().kind != semicolonToken {
+			break
+		}
+		p.index++
+	}
+
+	if _, err := p.expect(endToken); err != nil {
+		return game{}, err
+	}
+
 	return game{id, hands}, nil
 }
 
-func isGamePossible(g game, maxRed int, maxGreen int, maxBlue int) bool {
+func extractGameInfo(line string) (game, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return game{}, err
+	}
+
+	p := gameParser{tokens: tokens}
+	return p.parseGame()
+}
+
+// Parses a bag description such as "red=12,green=13,blue=14"
+func parseBag(str string) (hand, error) {
+	bag := hand{}
+	for _, part := range strings.Split(str, ",") {
+		colour, countStr, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found || colour == "" {
+			return hand{}, fmt.Errorf("invalid bag entry '%s', expected <colour>=<count>", part)
+		}
+
+		n, err := strconv.Atoi(countStr)
+		if err != nil {
+			return hand{}, err
+		}
+		bag[colour] = n
+	}
+	return bag, nil
+}
+
+// Colours not in the bag are treated as having no cubes
+func isGamePossible(g game, bag hand) bool {
 	for _, h := range g.hands {
-		if h.red > maxRed || h.green > maxGreen || h.blue > maxBlue {
-			return false
+		for colour, n := range h {
+			if n > bag[colour] {
+				return false
+			}
 		}
 	}
 	return true
 }
 
 // Time taken: 19 minutes
-func Part1() (string, error) {
+func Part1(args []string) (string, error) {
+	flags := flag.NewFlagSet("day2 part1", flag.ContinueOnError)
+	bagStr := flags.String("bag", "red=12,green=13,blue=14", "the cubes in the bag, as <colour>=<count> pairs")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	bag, err := parseBag(*bagStr)
+	if err != nil {
+		return "", err
+	}
+
 	lines, err := shared.ReadFileLines("days/day2/input.txt")
 	if err != nil {
 		return "", err
 	}
 
-	var games []game
-	for _, line := range lines {
+	sum, err := shared.ParallelSum(lines, func(line string) (int, error) {
 		g, err := extractGameInfo(line)
 		if err != nil {
-			return "", err
+			return 0, err
 		}
-		games = append(games, g)
-	}
 
-	sum := 0
-	for _, g := range games {
-		if isGamePossible(g, 12, 13, 14) {
-			sum += g.id
+		if isGamePossible(g, bag) {
+			return g.id, nil
 		}
+		return 0, nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	return strconv.Itoa(sum), nil
 }
 
-func maxRedCubes(g game) int {
-	m := 1
+// Returns the fewest cubes of each colour that could have been in the bag
+func minimalBag(g game) hand {
+	bag := hand{}
 	for _, h := range g.hands {
-		if m < h.red {
-			m = h.red
+		for colour, n := range h {
+			bag[colour] = max(bag[colour], n)
 		}
 	}
-	return m
+	return bag
 }
 
-func maxGreenCubes(g game) int {
-	m := 1
-	for _, h := range g.hands {
-		if m < h.green {
-			m = h.green
-		}
+// Returns the product of the number of cubes of each colour in the bag
+func bagPower(bag hand) int {
+	power := 1
+	for _, n := range bag {
+		power *= n
 	}
-	return m
-}
-
-func maxBlueCubes(g game) int {
-	m := 1
-	for _, h := range g.hands {
-		if m < h.blue {
-			m = h.blue
-		}
-	}
-	return m
+	return power
 }
 
+// Returns the product of the minimal number of cubes over all colours seen in the game
 func minCubePower(g game) int {
-	return maxRedCubes(g) * maxGreenCubes(g) * maxBlueCubes(g)
+	return bagPower(minimalBag(g))
 }
 
 // Time taken: 12 minutes
@@ -151,19 +325,301 @@ func Part2() (string, error) {
 		return "", err
 	}
 
-	var games []game
-	for _, line := range lines {
+	sum, err := shared.ParallelSum(lines, func(line string) (int, error) {
 		g, err := extractGameInfo(line)
 		if err != nil {
-			return "", err
+			return 0, err
+		}
+
+		return minCubePower(g), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(sum), nil
+}
+
+// Formats a bag in the same form accepted by parseBag, with colours sorted
+func formatBag(bag hand) string {
+	colours := make([]string, 0, len(bag))
+	for colour := range bag {
+		colours = append(colours, colour)
+	}
+	sort.Strings(colours)
+
+	parts := make([]string, len(colours))
+	for i, colour := range colours {
+		parts[i] = fmt.Sprintf("%s=%d", colour, bag[colour])
+	}
+	return strings.Join(parts, ",")
+}
+
+func allColours(games []game) []string {
+	seen := map[string]bool{}
+	for _, g := range games {
+		for _, h := range g.hands {
+			for colour := range h {
+				seen[colour] = true
+			}
+		}
+	}
+
+	colours := make([]string, 0, len(seen))
+	for colour := range seen {
+		colours = append(colours, colour)
+	}
+	sort.Strings(colours)
+	return colours
+}
+
+// Returns the smallest bag with which every game would have been possible
+func minimalBagForAllGames(games []game) hand {
+	bag := hand{}
+	for _, g := range games {
+		for colour, n := range minimalBag(g) {
+			bag[colour] = max(bag[colour], n)
 		}
-		games = append(games, g)
 	}
+	return bag
+}
 
+func sumOfFeasibleIds(minimalBags []hand, ids []int, bag hand) int {
 	sum := 0
+	for i, mb := range minimalBags {
+		feasible := true
+		for colour, n := range mb {
+			if n > bag[colour] {
+				feasible = false
+				break
+			}
+		}
+		if feasible {
+			sum += ids[i]
+		}
+	}
+	return sum
+}
+
+// Finds the bag containing at most budget cubes in total that maximizes the
+// sum of the IDs of the possible games. Returns the bag and that sum.
+func bestBagForBudget(games []game, budget int) (hand, int) {
+	colours := allColours(games)
+	minimalBags := make([]hand, len(games))
+	ids := make([]int, len(games))
+	for i, g := range games {
+		minimalBags[i] = minimalBag(g)
+		ids[i] = g.id
+	}
+
+	// It only ever helps to have exactly as many cubes of a colour as some
+	// game needs, so those are the only counts worth trying
+	candidates := make([][]int, len(colours))
+	for i, colour := range colours {
+		seen := map[int]bool{0: true}
+		candidates[i] = []int{0}
+		for _, mb := range minimalBags {
+			if n := mb[colour]; !seen[n] {
+				seen[n] = true
+				candidates[i] = append(candidates[i], n)
+			}
+		}
+		sort.Ints(candidates[i])
+	}
+
+	// needsFrom[g][i] is how many cubes game g needs of colour i and all later colours
+	needsFrom := make([][]int, len(games))
+	for g, mb := range minimalBags {
+		needsFrom[g] = make([]int, len(colours)+1)
+		for i := len(colours) - 1; i >= 0; i-- {
+			needsFrom[g][i] = needsFrom[g][i+1] + mb[colours[i]]
+		}
+	}
+
+	bestBag, bestSum, bestTotal := hand{}, -1, 0
+	bag := hand{}
+
+	// Searches the counts for colour i onwards, where alive holds the games
+	// that are still possible with the counts chosen so far. The search is
+	// exponential in the number of colours, so it is cut short whenever the
+	// games that could still fit in the remaining budget can't beat the best
+	// bag found so far.
+	var search func(i int, remaining int, alive []int)
+	search = func(i int, remaining int, alive []int) {
+		bound := 0
+		for _, g := range alive {
+			if needsFrom[g][i] <= remaining {
+				bound += ids[g]
+			}
+		}
+		used := budget - remaining
+		if bound < bestSum || (bound == bestSum && used >= bestTotal) {
+			return
+		}
+
+		if i == len(colours)-1 {
+			// Any cubes left over may as well go to the last colour, though
+			// only as many as some game needs are worth reporting
+			for _, c := range candidates[i] {
+				if c <= remaining {
+					bag[colours[i]] = c
+				}
+			}
+
+			sum := sumOfFeasibleIds(minimalBags, ids, bag)
+			total := used + bag[colours[i]]
+
+			// Prefer bags that need fewer cubes when the sums are equal
+			if sum > bestSum || (sum == bestSum && total < bestTotal) {
+				bestBag, bestSum, bestTotal = hand{}, sum, total
+				for colour, n := range bag {
+					bestBag[colour] = n
+				}
+			}
+			return
+		}
+
+		// Any other count could be lowered to the next count a possible
+		// game needs without losing any games
+		for _, c := range candidates[i] {
+			if c > remaining {
+				break
+			}
+			useful := c == 0
+			next := []int{}
+			for _, g := range alive {
+				n := minimalBags[g][colours[i]]
+				useful = useful || n == c
+				if n <= c {
+					next = append(next, g)
+				}
+			}
+			if !useful {
+				continue
+			}
+			bag[colours[i]] = c
+			search(i+1, remaining-c, next)
+		}
+	}
+
+	if len(colours) == 0 {
+		return hand{}, sumOfFeasibleIds(minimalBags, ids, hand{})
+	}
+	alive := make([]int, len(games))
+	for g := range alive {
+		alive[g] = g
+	}
+	search(0, budget, alive)
+	return bestBag, bestSum
+}
+
+func readGames(lines []string) ([]game, error) {
+	return shared.ParallelMap(lines, extractGameInfo)
+}
+
+// Prints the minimal bag for each game and whether it is possible, followed
+// by the minimal bag for all games and the best bag for a total cube budget
+func Report(args []string) (string, error) {
+	flags := flag.NewFlagSet("day2 report", flag.ContinueOnError)
+	bagStr := flags.String("bag", "red=12,green=13,blue=14", "the cubes in the bag, as <colour>=<count> pairs")
+	budget := flags.Int("budget", -1, "total number of cubes allowed when finding the best bag (default is the size of --bag)")
+	asCSV := flags.Bool("csv", false, "output as CSV instead of a table")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	bag, err := parseBag(*bagStr)
+	if err != nil {
+		return "", err
+	}
+
+	if *budget < 0 {
+		*budget = 0
+		for _, n := range bag {
+			*budget += n
+		}
+	}
+
+	lines, err := shared.ReadFileLines("days/day2/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	games, err := readGames(lines)
+	if err != nil {
+		return "", err
+	}
+
+	colours := allColours(games)
+
+	header := append([]string{"game"}, colours...)
+	header = append(header, "power", "feasible")
+	rows := [][]string{header}
+
+	addRow := func(name string, b hand, power int, feasible string) {
+		row := []string{name}
+		for _, colour := range colours {
+			row = append(row, strconv.Itoa(b[colour]))
+		}
+		row = append(row, strconv.Itoa(power), feasible)
+		rows = append(rows, row)
+	}
+
+	// The power only covers the colours seen in each game, the same as part 2
 	for _, g := range games {
-		sum += minCubePower(g)
+		addRow(strconv.Itoa(g.id), minimalBag(g), minCubePower(g), strconv.FormatBool(isGamePossible(g, bag)))
 	}
 
-	return strconv.Itoa(sum), nil
+	allBag := minimalBagForAllGames(games)
+	bestBag, bestSum := bestBagForBudget(games, *budget)
+
+	var sb strings.Builder
+	if *asCSV {
+		addRow("all", allBag, bagPower(allBag), "")
+		addRow(fmt.Sprintf("best-%d", *budget), bestBag, bagPower(bestBag), "")
+		w := csv.NewWriter(&sb)
+		if err := w.WriteAll(rows); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	}
+
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', tabwriter.AlignRight)
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t\n", strings.Join(row, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	feasibleSum := 0
+	for _, g := range games {
+		if isGamePossible(g, bag) {
+			feasibleSum += g.id
+		}
+	}
+
+	fmt.Fprintf(&sb, "\nSum of feasible IDs with %s: %d\n", formatBag(bag), feasibleSum)
+	fmt.Fprintf(&sb, "Minimal bag for all games to be possible: %s\n", formatBag(allBag))
+	fmt.Fprintf(&sb, "Best bag with at most %d cubes: %s (sum of feasible IDs %d)", *budget, formatBag(bestBag), bestSum)
+
+	return sb.String(), nil
+}
+
+// Generates game records with between one and six draws of red, green and blue cubes
+func Generate(r *rand.Rand, size int) []string {
+	colours := []string{"red", "green", "blue"}
+	lines := make([]string, size)
+	for i := range lines {
+		draws := []string{}
+		for d := 1 + r.Intn(6); d > 0; d-- {
+			cubes := []string{}
+			for _, c := range r.Perm(len(colours))[:1+r.Intn(len(colours))] {
+				cubes = append(cubes, fmt.Sprintf("%d %s", 1+r.Intn(20), colours[c]))
+			}
+			draws = append(draws, strings.Join(cubes, ", "))
+		}
+		lines[i] = fmt.Sprintf("Game %d: %s", i+1, strings.Join(draws, "; "))
+	}
+	return lines
 }
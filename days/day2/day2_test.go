@@ -0,0 +1,107 @@
+package day2
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExtractGameInfo(t *testing.T) {
+	tests := []struct {
+		line string
+		want game
+	}{
+		{
+			line: "Game 1: 3 blue, 4 red; 1 red, 2 green, 6 blue; 2 green",
+			want: game{1, []hand{{"blue": 3, "red": 4}, {"red": 1, "green": 2, "blue": 6}, {"green": 2}}},
+		},
+		{
+			line: "Game 2: 1 red,2 blue",
+			want: game{2, []hand{{"red": 1, "blue": 2}}},
+		},
+		{
+			line: "  Game   3 :1 red ;  2\tblue  ",
+			want: game{3, []hand{{"red": 1}, {"blue": 2}}},
+		},
+		{
+			line: "Game 4:5 pink",
+			want: game{4, []hand{{"pink": 5}}},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := extractGameInfo(tt.line)
+		if err != nil {
+			t.Errorf("extractGameInfo(%q) returned error %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractGameInfo(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestExtractGameInfoErrors(t *testing.T) {
+	tests := []struct {
+		line    string
+		column  int
+		message string
+	}{
+		{"Game 1 3 blue", 8, `expected ':' but found "3"`},
+		{"Game 1: 3 blue, 4 blue", 19, "colour blue appears more than once in the same draw"},
+		{"Game 1: 3 blue; ", 17, "expected number but found end of line"},
+		{"Game 1: 3 blue!", 15, "unexpected character '!'"},
+		{"Gam 1: 3 blue", 1, `expected "Game" but found "Gam"`},
+		{"Game 99999999999999999999: 1 red", 6, `invalid number "99999999999999999999"`},
+		{"Game 1: blue", 9, `expected number but found "blue"`},
+		{"Game 1: 3 blue 4 red", 16, `expected end of line but found "4"`},
+		{"", 1, "expected word but found end of line"},
+	}
+
+	for _, tt := range tests {
+		_, err := extractGameInfo(tt.line)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("extractGameInfo(%q) returned %v, want a ParseError", tt.line, err)
+			continue
+		}
+		if parseErr.Column != tt.column || parseErr.Message != tt.message {
+			t.Errorf("extractGameInfo(%q) error = column %d %q, want column %d %q", tt.line, parseErr.Column, parseErr.Message, tt.column, tt.message)
+		}
+	}
+}
+
+func FuzzExtractGameInfo(f *testing.F) {
+	seeds := []string{
+		"Game 1: 3 blue, 4 red; 1 red, 2 green, 6 blue; 2 green",
+		"Game 1: 13 red, 18 green; 5 green, 3 red, 5 blue; 5 green, 9 red, 6 blue; 3 blue, 3 green",
+		"Game 2: 2 green, 3 blue, 5 red; 9 green, 4 red, 2 blue; 4 green, 3 blue; 2 blue, 3 red; 5 red, 3 blue, 9 green; 9 green, 5 red, 2 blue",
+		"Game 1 3 blue",
+		"Game 1: 1 red,2 blue",
+		"  Game   1 :  1 red ;  2\tblue  ",
+		"Game 1: 3 blue, 4 blue",
+		"Game 1: 3 blue;",
+		"Game : ;,",
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		g, err := extractGameInfo(line)
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("extractGameInfo(%q) returned %v, want a ParseError", line, err)
+			}
+			if parseErr.Column < 1 || parseErr.Column > len(line)+1 {
+				t.Fatalf("extractGameInfo(%q) reported column %d outside the line", line, parseErr.Column)
+			}
+			return
+		}
+		if len(g.hands) == 0 {
+			t.Fatalf("extractGameInfo(%q) succeeded with no draws", line)
+		}
+	})
+}
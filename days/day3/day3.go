@@ -1,8 +1,15 @@
 package day3
 
 import (
+	"flag"
+	"fmt"
+	"html"
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
+	"sort"
 	"strconv"
+	"strings"
+	"text/tabwriter"
 )
 
 type PartNumber struct {
@@ -43,17 +50,6 @@ func findNextNonDigit(line string, index int) int {
 	return len(line)
 }
 
-func isNextToSymbol(lines []string, row int, s int, e int) bool {
-	for j := max(0, row-1); j < min(row+2, len(lines)); j++ {
-		for i := max(0, s-1); i < min(e+1, len(lines[j])); i++ {
-			if isSymbol(lines[j][i]) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 func extractPartNumbers(lines []string) ([]PartNumber, error) {
 	partNumbers := []PartNumber{}
 
@@ -90,32 +86,77 @@ func extractPartNumbers(lines []string) ([]PartNumber, error) {
 	return partNumbers, nil
 }
 
-func extractGears(lines []string) []Gear {
-	gears := []Gear{}
+// An engine schematic along with an index from each cell to the part number
+// occupying it, so that the numbers next to a cell can be found directly
+type Schematic struct {
+	lines       []string
+	partNumbers []PartNumber
+	// The index into partNumbers of the number covering each cell, or -1
+	cells [][]int
+}
+
+func readSchematic(lines []string) (Schematic, error) {
+	partNumbers, err := extractPartNumbers(lines)
+	if err != nil {
+		return Schematic{}, err
+	}
 
+	cells := make([][]int, len(lines))
 	for y, line := range lines {
-		for x := 0; x < len(line); x++ {
-			if line[x] == '*' {
-				gears = append(gears, Gear{x, y})
-			}
+		cells[y] = make([]int, len(line))
+		for x := range cells[y] {
+			cells[y][x] = -1
+		}
+	}
+	for i, partNumber := range partNumbers {
+		for x := partNumber.s; x < partNumber.e; x++ {
+			cells[partNumber.row][x] = i
 		}
 	}
 
-	return gears
+	return Schematic{lines, partNumbers, cells}, nil
 }
 
-func findNeighbouringPartNumbers(partNumbers []PartNumber, x int, y int) []PartNumber {
-	neighbours := []PartNumber{}
+// Returns the indexes of the part numbers in any of the eight cells around (x, y).
+// A number is only included once even if it covers several of those cells.
+func (s Schematic) findNeighbouringPartNumbers(x int, y int) []int {
+	neighbours := []int{}
+	for j := max(0, y-1); j < min(y+2, len(s.cells)); j++ {
+		for i := max(0, x-1); i < min(x+2, len(s.cells[j])); i++ {
+			n := s.cells[j][i]
+			if n == -1 || (j == y && i == x) {
+				continue
+			}
 
-	for _, partNumber := range partNumbers {
-		if partNumber.row >= y-1 && partNumber.row <= y+1 && partNumber.s <= x+1 && partNumber.e > x-1 {
-			neighbours = append(neighbours, partNumber)
+			seen := false
+			for _, m := range neighbours {
+				if m == n {
+					seen = true
+				}
+			}
+			if !seen {
+				neighbours = append(neighbours, n)
+			}
 		}
 	}
-
 	return neighbours
 }
 
+// Returns whether each part number is next to a symbol
+func (s Schematic) findValidPartNumbers() []bool {
+	valid := make([]bool, len(s.partNumbers))
+	for y, line := range s.lines {
+		for x := 0; x < len(line); x++ {
+			if isSymbol(line[x]) {
+				for _, n := range s.findNeighbouringPartNumbers(x, y) {
+					valid[n] = true
+				}
+			}
+		}
+	}
+	return valid
+}
+
 // Time taken: 16 minutes
 func Part1() (string, error) {
 	lines, err := shared.ReadFileLines("days/day3/input.txt")
@@ -123,15 +164,15 @@ func Part1() (string, error) {
 		return "", err
 	}
 
-	partNumbers, err := extractPartNumbers(lines)
+	schematic, err := readSchematic(lines)
 	if err != nil {
 		return "", err
 	}
 
 	partNumbersSum := 0
-	for _, partNumber := range partNumbers {
-		if isNextToSymbol(lines, partNumber.row, partNumber.s, partNumber.e) {
-			partNumbersSum += partNumber.number
+	for i, valid := range schematic.findValidPartNumbers() {
+		if valid {
+			partNumbersSum += schematic.partNumbers[i].number
 		}
 	}
 
@@ -145,20 +186,458 @@ func Part2() (string, error) {
 		return "", err
 	}
 
-	partNumbers, err := extractPartNumbers(lines)
+	schematic, err := readSchematic(lines)
 	if err != nil {
 		return "", err
 	}
 
-	gears := extractGears(lines)
-
 	gearRatiosSum := 0
-	for _, gear := range gears {
-		neighbours := findNeighbouringPartNumbers(partNumbers, gear.x, gear.y)
-		if len(neighbours) == 2 {
-			gearRatiosSum += neighbours[0].number * neighbours[1].number
-		}
+	for _, v := range schematic.findGearValues(defaultGearRule) {
+		gearRatiosSum += v
 	}
 
 	return strconv.Itoa(gearRatiosSum), nil
 }
+
+// Generates a size by size schematic of numbers and symbols
+func Generate(r *rand.Rand, size int) []string {
+	symbols := "*#+$/@=%&-"
+	grid := make([][]byte, size)
+	for y := range grid {
+		grid[y] = []byte(strings.Repeat(".", size))
+	}
+
+	for y := range grid {
+		x := r.Intn(4)
+		for x < size {
+			if r.Intn(5) == 0 {
+				grid[y][x] = symbols[r.Intn(len(symbols))]
+				x += 1 + r.Intn(4)
+				continue
+			}
+
+			n := strconv.Itoa(1 + r.Intn(999))
+			if x+len(n) > size {
+				break
+			}
+			copy(grid[y][x:], n)
+			// Leave at least one cell before the next number so they don't merge
+			x += len(n) + 1 + r.Intn(4)
+		}
+	}
+
+	lines := make([]string, size)
+	for y := range grid {
+		lines[y] = string(grid[y])
+	}
+	return lines
+}
+
+type Symbol struct {
+	c byte
+	x int
+	y int
+}
+
+// Returns every symbol in the schematic whose character is in symbols, or
+// every symbol at all if symbols is empty
+func (s Schematic) findSymbols(symbols string) []Symbol {
+	found := []Symbol{}
+	for y, line := range s.lines {
+		for x := 0; x < len(line); x++ {
+			if isSymbol(line[x]) && (symbols == "" || strings.IndexByte(symbols, line[x]) != -1) {
+				found = append(found, Symbol{line[x], x, y})
+			}
+		}
+	}
+	return found
+}
+
+// Returns the indexes of the part numbers next to any of the given symbols, in schematic order
+func (s Schematic) findNumbersAdjacentTo(symbols string) []int {
+	adjacent := make([]bool, len(s.partNumbers))
+	for _, symbol := range s.findSymbols(symbols) {
+		for _, n := range s.findNeighbouringPartNumbers(symbol.x, symbol.y) {
+			adjacent[n] = true
+		}
+	}
+
+	numbers := []int{}
+	for n, isAdjacent := range adjacent {
+		if isAdjacent {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
+
+// Returns the indexes of the part numbers that aren't next to any symbol
+func (s Schematic) findIsolatedNumbers() []int {
+	numbers := []int{}
+	for n, valid := range s.findValidPartNumbers() {
+		if !valid {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers
+}
+
+type SymbolStats struct {
+	symbol byte
+	// How many times the symbol appears
+	count int
+	// How many distinct numbers are next to at least one of the symbols
+	numAdjacent int
+	// The sum of those numbers
+	adjacentSum int
+}
+
+// Returns statistics for each kind of symbol in the schematic, ordered by symbol
+func (s Schematic) aggregateBySymbol() []SymbolStats {
+	counts := map[byte]int{}
+	for _, symbol := range s.findSymbols("") {
+		counts[symbol.c]++
+	}
+
+	stats := []SymbolStats{}
+	for c, count := range counts {
+		st := SymbolStats{symbol: c, count: count}
+		for _, n := range s.findNumbersAdjacentTo(string(c)) {
+			st.numAdjacent++
+			st.adjacentSum += s.partNumbers[n].number
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].symbol < stats[j].symbol
+	})
+	return stats
+}
+
+// A symbol counts as a gear if it is next to exactly numNeighbours part
+// numbers, and its value is those numbers combined with combine
+type GearRule struct {
+	symbol        byte
+	numNeighbours int
+	combine       func(values []int) int
+}
+
+func sumValues(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func multiplyValues(values []int) int {
+	total := 1
+	for _, v := range values {
+		total *= v
+	}
+	return total
+}
+
+var combineFunctions = map[string]func([]int) int{
+	"sum":     sumValues,
+	"product": multiplyValues,
+}
+
+var defaultGearRule = GearRule{'*', 2, multiplyValues}
+
+// A gear along with the numbers it connects
+type gearInfo struct {
+	gear    Gear
+	numbers []int
+	value   int
+}
+
+func (g gearInfo) describe() string {
+	numbers := make([]string, len(g.numbers))
+	for i, n := range g.numbers {
+		numbers[i] = strconv.Itoa(n)
+	}
+	return fmt.Sprintf("gear at row %d, column %d: numbers %s, value %d", g.gear.y, g.gear.x, strings.Join(numbers, ", "), g.value)
+}
+
+// Returns every gear according to the rule, in schematic order
+func (s Schematic) findGears(rule GearRule) []gearInfo {
+	gears := []gearInfo{}
+	for _, symbol := range s.findSymbols(string(rule.symbol)) {
+		neighbours := s.findNeighbouringPartNumbers(symbol.x, symbol.y)
+		if len(neighbours) != rule.numNeighbours {
+			continue
+		}
+
+		numbers := make([]int, len(neighbours))
+		for i, n := range neighbours {
+			numbers[i] = s.partNumbers[n].number
+		}
+		gears = append(gears, gearInfo{Gear{symbol.x, symbol.y}, numbers, rule.combine(numbers)})
+	}
+	return gears
+}
+
+// Returns the value of every gear according to the rule
+func (s Schematic) findGearValues(rule GearRule) []int {
+	values := []int{}
+	for _, g := range s.findGears(rule) {
+		values = append(values, g.value)
+	}
+	return values
+}
+
+// Adds the flags that pick a gear rule, and returns a function that builds
+// the rule once the flags have been parsed
+func addGearRuleFlags(flags *flag.FlagSet) func() (GearRule, error) {
+	gearSymbol := flags.String("symbol", "*", "the symbol that gears use")
+	numNeighbours := flags.Int("neighbours", 2, "how many numbers a gear must be next to")
+	combineName := flags.String("combine", "product", "how to combine a gear's numbers: sum or product")
+
+	return func() (GearRule, error) {
+		if len(*gearSymbol) != 1 || !isSymbol((*gearSymbol)[0]) {
+			return GearRule{}, fmt.Errorf("invalid gear symbol: '%s'", *gearSymbol)
+		}
+		combine, ok := combineFunctions[*combineName]
+		if !ok {
+			return GearRule{}, fmt.Errorf("Unrecognised combine function: %s", *combineName)
+		}
+		return GearRule{(*gearSymbol)[0], *numNeighbours, combine}, nil
+	}
+}
+
+func (s Schematic) formatPartNumbers(indexes []int) string {
+	var sb strings.Builder
+	sum := 0
+	for _, n := range indexes {
+		pn := s.partNumbers[n]
+		fmt.Fprintf(&sb, "%d at row %d, columns %d-%d\n", pn.number, pn.row, pn.s, pn.e-1)
+		sum += pn.number
+	}
+	fmt.Fprintf(&sb, "%d numbers, sum %d", len(indexes), sum)
+	return sb.String()
+}
+
+// Answers questions about the schematic. The available queries are:
+//
+//	adjacent [--symbols S]   numbers next to any of the symbols in S, or any symbol at all
+//	isolated                 numbers not next to any symbol
+//	by-symbol                counts and sums of adjacent numbers for each kind of symbol
+//	gears [--symbol C] [--neighbours N] [--combine sum|product]
+//	                         values of symbols next to exactly N numbers
+func Query(args []string) (string, error) {
+	if len(args) < 1 {
+		return "", fmt.Errorf("Usage: ./run 3 query <adjacent|isolated|by-symbol|gears> [options]")
+	}
+	query := args[0]
+
+	flags := flag.NewFlagSet("day3 query "+query, flag.ContinueOnError)
+	symbols := flags.String("symbols", "", "symbols to look for, defaults to all symbols")
+	gearRule := addGearRuleFlags(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day3/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	schematic, err := readSchematic(lines)
+	if err != nil {
+		return "", err
+	}
+
+	if query == "adjacent" {
+		return schematic.formatPartNumbers(schematic.findNumbersAdjacentTo(*symbols)), nil
+	} else if query == "isolated" {
+		return schematic.formatPartNumbers(schematic.findIsolatedNumbers()), nil
+	} else if query == "by-symbol" {
+		var sb strings.Builder
+		w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(w, "symbol\tcount\tadjacent numbers\tsum\t\n")
+		for _, st := range schematic.aggregateBySymbol() {
+			fmt.Fprintf(w, "%c\t%d\t%d\t%d\t\n", st.symbol, st.count, st.numAdjacent, st.adjacentSum)
+		}
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	} else if query == "gears" {
+		rule, err := gearRule()
+		if err != nil {
+			return "", err
+		}
+
+		values := schematic.findGearValues(rule)
+		return fmt.Sprintf("%d gears, sum of values %d", len(values), sumValues(values)), nil
+	}
+
+	return "", fmt.Errorf("Unrecognised query: %s", query)
+}
+
+type cellKind int
+
+const (
+	plainCell cellKind = iota
+	validNumberCell
+	invalidNumberCell
+	gearCell
+)
+
+const (
+	ansiReset   = "\033[0m"
+	ansiValid   = "\033[32m"
+	ansiInvalid = "\033[31m"
+	ansiGear    = "\033[1;33m"
+)
+
+// Works out how each cell of the schematic should be shown, and which gears
+// there are according to the rule
+func (s Schematic) classifyCells(rule GearRule) ([][]cellKind, []gearInfo) {
+	kinds := make([][]cellKind, len(s.lines))
+	for y, line := range s.lines {
+		kinds[y] = make([]cellKind, len(line))
+	}
+
+	valid := s.findValidPartNumbers()
+	for i, pn := range s.partNumbers {
+		kind := invalidNumberCell
+		if valid[i] {
+			kind = validNumberCell
+		}
+		for x := pn.s; x < pn.e; x++ {
+			kinds[pn.row][x] = kind
+		}
+	}
+
+	gears := s.findGears(rule)
+	for _, g := range gears {
+		kinds[g.gear.y][g.gear.x] = gearCell
+	}
+
+	return kinds, gears
+}
+
+func (s Schematic) renderANSI(rule GearRule) string {
+	kinds, gears := s.classifyCells(rule)
+	colours := map[cellKind]string{
+		validNumberCell:   ansiValid,
+		invalidNumberCell: ansiInvalid,
+		gearCell:          ansiGear,
+	}
+
+	var sb strings.Builder
+	for y, line := range s.lines {
+		current := plainCell
+		for x := 0; x < len(line); x++ {
+			if kinds[y][x] != current {
+				if current != plainCell {
+					sb.WriteString(ansiReset)
+				}
+				sb.WriteString(colours[kinds[y][x]])
+				current = kinds[y][x]
+			}
+			sb.WriteByte(line[x])
+		}
+		if current != plainCell {
+			sb.WriteString(ansiReset)
+		}
+		sb.WriteByte('\n')
+	}
+
+	fmt.Fprintf(&sb, "\n%svalid part number%s  %sinvalid part number%s  %sgear%s\n",
+		ansiValid, ansiReset, ansiInvalid, ansiReset, ansiGear, ansiReset)
+	for _, g := range gears {
+		sb.WriteString(g.describe() + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (s Schematic) renderHTML(rule GearRule) string {
+	kinds, gears := s.classifyCells(rule)
+	gearsByPosition := map[Gear]gearInfo{}
+	for _, g := range gears {
+		gearsByPosition[g.gear] = g
+	}
+	classes := map[cellKind]string{
+		validNumberCell:   "valid",
+		invalidNumberCell: "invalid",
+		gearCell:          "gear",
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Engine schematic</title>
+<style>
+body { background: #111; color: #777; font-family: monospace; }
+.valid { color: #4c4; }
+.invalid { color: #e44; }
+.gear { color: #fd3; font-weight: bold; cursor: help; }
+</style>
+</head>
+<body>
+<pre>
+`)
+	for y, line := range s.lines {
+		for x := 0; x < len(line); {
+			// Group runs of the same kind of cell, except gears which each need their own title
+			kind := kinds[y][x]
+			end := x + 1
+			for kind != gearCell && end < len(line) && kinds[y][end] == kind {
+				end++
+			}
+
+			text := html.EscapeString(line[x:end])
+			if kind == plainCell {
+				sb.WriteString(text)
+			} else if kind == gearCell {
+				fmt.Fprintf(&sb, `<span class="gear" title="%s">%s</span>`, html.EscapeString(gearsByPosition[Gear{x, y}].describe()), text)
+			} else {
+				fmt.Fprintf(&sb, `<span class="%s">%s</span>`, classes[kind], text)
+			}
+			x = end
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("</pre>\n<h2>Legend</h2>\n<p><span class=\"valid\">valid part number</span> <span class=\"invalid\">invalid part number</span> <span class=\"gear\">gear</span></p>\n<ul>\n")
+	for _, g := range gears {
+		fmt.Fprintf(&sb, "<li>%s</li>\n", html.EscapeString(g.describe()))
+	}
+	sb.WriteString("</ul>\n</body>\n</html>")
+	return sb.String()
+}
+
+// Prints the schematic with valid and invalid part numbers and gears
+// highlighted. Gears are picked with the same flags as the gears query.
+func Render(args []string) (string, error) {
+	flags := flag.NewFlagSet("day3 render", flag.ContinueOnError)
+	asHTML := flags.Bool("html", false, "output an HTML page instead of using ANSI colours")
+	gearRule := addGearRuleFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	rule, err := gearRule()
+	if err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day3/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	schematic, err := readSchematic(lines)
+	if err != nil {
+		return "", err
+	}
+
+	if *asHTML {
+		return schematic.renderHTML(rule), nil
+	}
+	return schematic.renderANSI(rule), nil
+}
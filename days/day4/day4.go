@@ -1,11 +1,15 @@
 package day4
 
 import (
+	"flag"
 	"fmt"
+	"math/rand"
 	"regexp"
 	"robertbrignull/adventofcode2023/shared"
+	"robertbrignull/adventofcode2023/shared/containers"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 )
 
 type ScratchCard struct {
@@ -15,15 +19,6 @@ type ScratchCard struct {
 	numMatches     int
 }
 
-func isWinningNumber(winningNumbers []int, yourNumber int) bool {
-	for _, n := range winningNumbers {
-		if n == yourNumber {
-			return true
-		}
-	}
-	return false
-}
-
 func computeScratchCardPoints(numMatches int) int {
 	if numMatches == 0 {
 		return 0
@@ -35,10 +30,43 @@ func computeScratchCardPoints(numMatches int) int {
 	return points
 }
 
+// Real cards only use small numbers, so those are kept in a bitset. Anything
+// larger goes in a map, so that one huge number can't make the bitset huge.
+const maxBitSetValue = 1 << 12
+
+type numberSet struct {
+	small *containers.BitSet
+	large map[int]bool
+}
+
+func newNumberSet() numberSet {
+	return numberSet{containers.NewBitSet(100), make(map[int]bool)}
+}
+
+func (s numberSet) add(n int) {
+	if n >= 0 && n < maxBitSetValue {
+		s.small.Set(n)
+	} else {
+		s.large[n] = true
+	}
+}
+
+func (s numberSet) has(n int) bool {
+	if n >= 0 && n < maxBitSetValue {
+		return s.small.Has(n)
+	}
+	return s.large[n]
+}
+
 func computeNumMatches(winningNumbers []int, yourNumbers []int) int {
+	winning := newNumberSet()
+	for _, n := range winningNumbers {
+		winning.add(n)
+	}
+
 	numMatches := 0
 	for _, yn := range yourNumbers {
-		if isWinningNumber(winningNumbers, yn) {
+		if winning.has(yn) {
 			numMatches += 1
 		}
 	}
@@ -57,9 +85,10 @@ func extractNumbers(line string) ([]int, error) {
 	return ns, nil
 }
 
+var scratchCardRegexp = regexp.MustCompile(`Card +(\d+): ([\d ]+) \| ([\d ]+)`)
+
 func extractScratchCard(line string) (ScratchCard, error) {
-	re := regexp.MustCompile(`Card +(\d+): ([\d ]+) \| ([\d ]+)`)
-	match := re.FindStringSubmatch(line)
+	match := scratchCardRegexp.FindStringSubmatch(line)
 	if len(match) != 4 {
 		return ScratchCard{}, fmt.Errorf("invalid scratch card line: %s", line)
 	}
@@ -90,21 +119,60 @@ func extractScratchCard(line string) (ScratchCard, error) {
 }
 
 func extractScratchCards(lines []string) ([]ScratchCard, error) {
-	scratchCards := []ScratchCard{}
+	return shared.ParallelMap(lines, extractScratchCard)
+}
+
+// Records that one card won copies of a later card
+type Contribution struct {
+	from      int
+	to        int
+	numCopies int
+}
+
+// Returns how many copies of each card we end up with, and for each card the
+// contributions that earlier cards made to its copies
+func computeCopies(scratchCards []ScratchCard) ([]int, [][]Contribution) {
+	numCopies := make([]int, len(scratchCards))
+	contributions := make([][]Contribution, len(scratchCards))
+	for i := range numCopies {
+		// We gain one original copy
+		numCopies[i] += 1
+
+		for j := 0; j < scratchCards[i].numMatches; j++ {
+			if i+j+1 >= len(numCopies) {
+				break
+			}
+			numCopies[i+j+1] += numCopies[i]
+			contributions[i+j+1] = append(contributions[i+j+1], Contribution{i, i + j + 1, numCopies[i]})
+		}
+	}
+	return numCopies, contributions
+}
 
-	for _, line := range lines {
+// Time taken: 16 minutes
+func Part1() (string, error) {
+	lines, err := shared.ReadFileLines("days/day4/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	pointsSum, err := shared.ParallelSum(lines, func(line string) (int, error) {
 		scratchCard, err := extractScratchCard(line)
 		if err != nil {
-			return []ScratchCard{}, err
+			return 0, err
 		}
-		scratchCards = append(scratchCards, scratchCard)
+
+		return computeScratchCardPoints(scratchCard.numMatches), nil
+	})
+	if err != nil {
+		return "", err
 	}
 
-	return scratchCards, nil
+	return strconv.Itoa(pointsSum), nil
 }
 
-// Time taken: 16 minutes
-func Part1() (string, error) {
+// Time taken: 11 minutes
+func Part2() (string, error) {
 	lines, err := shared.ReadFileLines("days/day4/input.txt")
 	if err != nil {
 		return "", err
@@ -115,16 +183,57 @@ func Part1() (string, error) {
 		return "", err
 	}
 
-	pointsSum := 0
-	for _, scratchCard := range scratchCards {
-		pointsSum += computeScratchCardPoints(scratchCard.numMatches)
+	numCopies, _ := computeCopies(scratchCards)
+
+	cardsSum := 0
+	for _, c := range numCopies {
+		cardsSum += c
 	}
 
-	return strconv.Itoa(pointsSum), nil
+	return strconv.Itoa(cardsSum), nil
 }
 
-// Time taken: 11 minutes
-func Part2() (string, error) {
+// Generates scratch cards with 10 winning numbers and 25 of your numbers
+func Generate(r *rand.Rand, size int) []string {
+	formatNumbers := func(ns []int) string {
+		parts := make([]string, len(ns))
+		for i, n := range ns {
+			parts[i] = fmt.Sprintf("%2d", n+1)
+		}
+		return strings.Join(parts, " ")
+	}
+
+	width := len(strconv.Itoa(size))
+	lines := make([]string, size)
+	for i := range lines {
+		numbers := r.Perm(99)
+		winningNumbers := numbers[:10]
+		yourNumbers := numbers[10:35]
+
+		// Most cards don't win, and cards never win copies of cards past the
+		// end of the table, which keeps the number of copies from exploding
+		numMatches := 0
+		if r.Intn(3) == 0 {
+			numMatches = min(1+r.Intn(10), size-i-1)
+		}
+		for j, k := range r.Perm(25)[:numMatches] {
+			yourNumbers[k] = winningNumbers[j]
+		}
+
+		lines[i] = fmt.Sprintf("Card %*d: %s | %s", width, i+1, formatNumbers(winningNumbers), formatNumbers(yourNumbers))
+	}
+	return lines
+}
+
+// Shows how many copies of each card are won and where they came from, either
+// as a table or as a DOT graph of the contributions
+func Report(args []string) (string, error) {
+	flags := flag.NewFlagSet("day4 report", flag.ContinueOnError)
+	asDOT := flags.Bool("dot", false, "output the contribution graph in DOT format")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
 	lines, err := shared.ReadFileLines("days/day4/input.txt")
 	if err != nil {
 		return "", err
@@ -135,23 +244,107 @@ func Part2() (string, error) {
 		return "", err
 	}
 
-	numCopies := make([]int, len(scratchCards))
-	for i := range numCopies {
-		// We gain one original copy
-		numCopies[i] += 1
+	numCopies, contributions := computeCopies(scratchCards)
 
-		for j := 0; j < scratchCards[i].numMatches; j++ {
-			if i+j+1 >= len(numCopies) {
-				break
+	var sb strings.Builder
+	if *asDOT {
+		sb.WriteString("digraph scratchcards {\n")
+		for i, card := range scratchCards {
+			fmt.Fprintf(&sb, "  card%d [label=\"Card %d\\n%d matches\\n%d copies\"];\n", card.cardNumber, card.cardNumber, card.numMatches, numCopies[i])
+		}
+		for _, cs := range contributions {
+			for _, c := range cs {
+				fmt.Fprintf(&sb, "  card%d -> card%d [label=\"%d\"];\n", scratchCards[c.from].cardNumber, scratchCards[c.to].cardNumber, c.numCopies)
 			}
-			numCopies[i+j+1] += numCopies[i]
 		}
+		sb.WriteString("}")
+		return sb.String(), nil
 	}
 
-	cardsSum := 0
-	for _, c := range numCopies {
-		cardsSum += c
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "card\tmatches\tpoints\tcopies\tcopies won from\n")
+	totalPoints, totalCopies := 0, 0
+	for i, card := range scratchCards {
+		sources := make([]string, len(contributions[i]))
+		for j, c := range contributions[i] {
+			sources[j] = fmt.Sprintf("%d (%d)", scratchCards[c.from].cardNumber, c.numCopies)
+		}
+		points := computeScratchCardPoints(card.numMatches)
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\n", card.cardNumber, card.numMatches, points, numCopies[i], strings.Join(sources, ", "))
+		totalPoints += points
+		totalCopies += numCopies[i]
+	}
+	fmt.Fprintf(w, "total\t\t%d\t%d\t\n", totalPoints, totalCopies)
+	if err := w.Flush(); err != nil {
+		return "", err
 	}
 
-	return strconv.Itoa(cardsSum), nil
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// Returns the numbers that appear more than once in the list, in the order they repeat
+func findDuplicates(ns []int) []int {
+	seen := newNumberSet()
+	duplicates := []int{}
+	for _, n := range ns {
+		if seen.has(n) {
+			duplicates = append(duplicates, n)
+		}
+		seen.add(n)
+	}
+	return duplicates
+}
+
+// Checks the scratch cards for problems that could make the answers wrong.
+// Duplicate numbers make matches ambiguous and cards out of sequence break
+// the copying rules, so those are errors. Lists of different lengths to the
+// first card are unusual but harmless, so those are warnings.
+func validateScratchCards(scratchCards []ScratchCard) []shared.ValidationIssue {
+	issues := []shared.ValidationIssue{}
+	for i, card := range scratchCards {
+		line := i + 1
+
+		if card.cardNumber != i+1 {
+			issues = append(issues, shared.NewIssue(shared.Error, line, fmt.Sprintf("expected card %d but found card %d", i+1, card.cardNumber)))
+		}
+
+		for _, n := range findDuplicates(card.winningNumbers) {
+			issues = append(issues, shared.NewIssue(shared.Error, line, fmt.Sprintf("winning number %d appears more than once", n)))
+		}
+		for _, n := range findDuplicates(card.yourNumbers) {
+			issues = append(issues, shared.NewIssue(shared.Error, line, fmt.Sprintf("your number %d appears more than once", n)))
+		}
+
+		first := scratchCards[0]
+		if len(card.winningNumbers) != len(first.winningNumbers) {
+			issues = append(issues, shared.NewIssue(shared.Warning, line, fmt.Sprintf("card has %d winning numbers but the first card has %d", len(card.winningNumbers), len(first.winningNumbers))))
+		}
+		if len(card.yourNumbers) != len(first.yourNumbers) {
+			issues = append(issues, shared.NewIssue(shared.Warning, line, fmt.Sprintf("card has %d of your numbers but the first card has %d", len(card.yourNumbers), len(first.yourNumbers))))
+		}
+	}
+	return issues
+}
+
+// Reports any problems with the scratch cards. Fails if there are any
+// errors, or any warnings when --strict is given.
+func Validate(args []string) (string, error) {
+	flags := flag.NewFlagSet("day4 validate", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day4/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	scratchCards, err := extractScratchCards(lines)
+	if err != nil {
+		return "", err
+	}
+
+	issues := validateScratchCards(scratchCards)
+	return shared.ValidationReport(issues, fmt.Sprintf("%d cards", len(scratchCards)), *strict)
 }
@@ -1,8 +1,14 @@
 package day5
 
 import (
+	"flag"
 	"fmt"
+	"math"
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
+	"robertbrignull/adventofcode2023/shared/containers"
+	"robertbrignull/adventofcode2023/shared/interval"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -47,16 +53,45 @@ func (rm RangeMap) lookup(source int) (int, int) {
 	return source, nextSourceStart - source - 1
 }
 
+// Converts the range map into a piecewise linear map. Where entries overlap
+// the first one wins, just as in lookup.
+func (rm RangeMap) toPiecewiseLinearMap() (interval.PiecewiseLinearMap, error) {
+	pieces := []interval.Piece{}
+	covered := interval.NewIntervalSet()
+	for _, entry := range rm.entries {
+		source := interval.NewIntervalSet(interval.Interval{Start: entry.sourceStart, End: entry.sourceStart + entry.length})
+		for _, in := range source.Subtract(covered).Intervals() {
+			pieces = append(pieces, interval.Piece{Source: in, Offset: entry.destinationStart - entry.sourceStart})
+		}
+		covered = covered.Union(source)
+	}
+
+	// The pieces can't overlap as each one excludes everything before it
+	return interval.NewPiecewiseLinearMap(pieces)
+}
+
+// A map that converts values in one category to values in another
+type CategoryMap struct {
+	from     string
+	to       string
+	rangeMap RangeMap
+}
+
+// Returns the relation that takes each destination value back to the source
+// values that map to it. Unmapped values pass through unchanged in both directions.
+func (rm RangeMap) invert() (interval.Relation, error) {
+	m, err := rm.toPiecewiseLinearMap()
+	if err != nil {
+		return interval.Relation{}, err
+	}
+	return m.Inverse(), nil
+}
+
 type Almanac struct {
-	seeds                 Seeds
-	seedRanges            []SeedRange
-	seedSoilMap           RangeMap
-	soilFertilizerMap     RangeMap
-	fertilizerWaterMap    RangeMap
-	waterLightMap         RangeMap
-	lightTemperatureMap   RangeMap
-	temperaturHumidityMap RangeMap
-	humidityLocationMap   RangeMap
+	seeds      Seeds
+	seedRanges []SeedRange
+	// Maps keyed by the category they convert from
+	maps map[string][]CategoryMap
 }
 
 func readSeeds(line string) (Seeds, error) {
@@ -73,6 +108,9 @@ func readSeeds(line string) (Seeds, error) {
 
 func readSeedRanges(line string) ([]SeedRange, error) {
 	fields := strings.Fields(line[len("seeds: "):])
+	if len(fields)%2 != 0 {
+		return []SeedRange{}, fmt.Errorf("seed ranges need an even number of values but there are %d", len(fields))
+	}
 	seedRanges := []SeedRange{}
 	for i := 0; i < len(fields); i += 2 {
 		start, err := strconv.Atoi(fields[i])
@@ -113,8 +151,22 @@ func readRangeMap(lines []string) (RangeMap, error) {
 	return rangeMap, nil
 }
 
+// Parses a header like "seed-to-soil map:" into its source and destination categories
+func readMapName(line string) (string, string, error) {
+	name, ok := strings.CutSuffix(line, " map:")
+	if !ok {
+		return "", "", fmt.Errorf("invalid map name: %s", line)
+	}
+
+	from, to, ok := strings.Cut(name, "-to-")
+	if !ok || from == "" || to == "" {
+		return "", "", fmt.Errorf("invalid map name: %s", line)
+	}
+	return from, to, nil
+}
+
 func readAlmanac(lines []string) (Almanac, error) {
-	var almanac Almanac
+	almanac := Almanac{maps: make(map[string][]CategoryMap)}
 
 	seeds, err := readSeeds(lines[0])
 	if err != nil {
@@ -134,29 +186,22 @@ func readAlmanac(lines []string) (Almanac, error) {
 			break
 		}
 
-		mapName := lines[row]
+		from, to, err := readMapName(lines[row])
+		if err != nil {
+			return Almanac{}, err
+		}
+
 		rangeMap, err := readRangeMap(lines[row+1:])
 		if err != nil {
 			return Almanac{}, err
 		}
 
-		if mapName == "seed-to-soil map:" {
-			almanac.seedSoilMap = rangeMap
-		} else if mapName == "soil-to-fertilizer map:" {
-			almanac.soilFertilizerMap = rangeMap
-		} else if mapName == "fertilizer-to-water map:" {
-			almanac.fertilizerWaterMap = rangeMap
-		} else if mapName == "water-to-light map:" {
-			almanac.waterLightMap = rangeMap
-		} else if mapName == "light-to-temperature map:" {
-			almanac.lightTemperatureMap = rangeMap
-		} else if mapName == "temperature-to-humidity map:" {
-			almanac.temperaturHumidityMap = rangeMap
-		} else if mapName == "humidity-to-location map:" {
-			almanac.humidityLocationMap = rangeMap
-		} else {
-			return Almanac{}, fmt.Errorf("unknown map name: %s", mapName)
+		for _, existing := range almanac.maps[from] {
+			if existing.to == to {
+				return Almanac{}, fmt.Errorf("duplicate map from %s to %s", from, to)
+			}
 		}
+		almanac.maps[from] = append(almanac.maps[from], CategoryMap{from, to, rangeMap})
 
 		row += 2 + len(rangeMap.entries)
 	}
@@ -164,15 +209,44 @@ func readAlmanac(lines []string) (Almanac, error) {
 	return almanac, nil
 }
 
-func computeSeedResult(seed int, almanac Almanac) (int, int) {
-	soil, soilFV := almanac.seedSoilMap.lookup(seed)
-	fertilizer, fertilizerFV := almanac.soilFertilizerMap.lookup(soil)
-	water, waterFV := almanac.fertilizerWaterMap.lookup(fertilizer)
-	light, lightFV := almanac.waterLightMap.lookup(water)
-	temperature, temperatureFV := almanac.lightTemperatureMap.lookup(light)
-	humidity, humidityFV := almanac.temperaturHumidityMap.lookup(temperature)
-	location, locationFV := almanac.humidityLocationMap.lookup(humidity)
-	return location, min(soilFV, fertilizerFV, waterFV, lightFV, temperatureFV, humidityFV, locationFV)
+// Returns the shortest sequence of maps that converts from one category to another
+func (almanac Almanac) findChain(from string, to string) ([]CategoryMap, error) {
+	// Breadth first search, remembering the map used to reach each category
+	cameFrom := map[string]CategoryMap{}
+	visited := map[string]bool{from: true}
+	queue := containers.NewDeque[string]()
+	queue.PushBack(from)
+	for !visited[to] {
+		category, ok := queue.PopFront()
+		if !ok {
+			break
+		}
+		for _, m := range almanac.maps[category] {
+			if !visited[m.to] {
+				visited[m.to] = true
+				cameFrom[m.to] = m
+				queue.PushBack(m.to)
+			}
+		}
+	}
+
+	if !visited[to] {
+		return nil, fmt.Errorf("no chain of maps from %s to %s", from, to)
+	}
+
+	chain := []CategoryMap{}
+	for category := to; category != from; category = cameFrom[category].from {
+		chain = append([]CategoryMap{cameFrom[category]}, chain...)
+	}
+	return chain, nil
+}
+
+// Looks up a value through each map of the chain in turn
+func lookupChain(chain []CategoryMap, value int) int {
+	for _, m := range chain {
+		value, _ = m.rangeMap.lookup(value)
+	}
+	return value
 }
 
 // Time taken: 25 minutes
@@ -187,9 +261,14 @@ func Part1() (string, error) {
 		return "", err
 	}
 
+	chain, err := almanac.findChain("seed", "location")
+	if err != nil {
+		return "", err
+	}
+
 	lowestResult := -1
 	for _, seed := range almanac.seeds {
-		result, _ := computeSeedResult(seed, almanac)
+		result := lookupChain(chain, seed)
 		if lowestResult == -1 || result < lowestResult {
 			lowestResult = result
 		}
@@ -198,9 +277,75 @@ func Part1() (string, error) {
 	return strconv.Itoa(lowestResult), nil
 }
 
+// A range of values in some category, along with the seed that maps to the start of the range
+type TrackedInterval struct {
+	values    interval.Interval
+	seedStart int
+}
+
+// Maps every interval through the range map, splitting them wherever they
+// cross the boundary of an entry
+func propagateIntervals(tracked []TrackedInterval, rm RangeMap) ([]TrackedInterval, error) {
+	m, err := rm.toPiecewiseLinearMap()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []TrackedInterval{}
+	for _, t := range tracked {
+		for _, part := range m.Split(t.values) {
+			result = append(result, TrackedInterval{
+				values:    part.Source.Shift(part.Offset),
+				seedStart: t.seedStart + (part.Source.Start - t.values.Start),
+			})
+		}
+	}
+	return result, nil
+}
+
+// Returns the location intervals that the seed ranges map to, along with the seeds they come from
+func (almanac Almanac) computeLocationIntervals() ([]TrackedInterval, error) {
+	chain, err := almanac.findChain("seed", "location")
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := []TrackedInterval{}
+	for _, seedRange := range almanac.seedRanges {
+		tracked = append(tracked, TrackedInterval{
+			values:    interval.Interval{Start: seedRange.start, End: seedRange.start + seedRange.length},
+			seedStart: seedRange.start,
+		})
+	}
+
+	for _, m := range chain {
+		tracked, err = propagateIntervals(tracked, m.rangeMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tracked, nil
+}
+
+// Returns the lowest location of any seed in the seed ranges, and the seed that maps to it
+func findLowestLocation(locations []TrackedInterval) (int, int, error) {
+	if len(locations) == 0 {
+		return 0, 0, fmt.Errorf("no seed ranges")
+	}
+
+	lowest := locations[0]
+	for _, t := range locations {
+		if t.values.Start < lowest.values.Start {
+			lowest = t
+		}
+	}
+	return lowest.values.Start, lowest.seedStart, nil
+}
+
 // Original time taken to get answer: 15 minutes
 // Execution time before optimization: 352 seconds
 // Execution time after optimization: 0.2 seconds
+// Execution time with interval propagation: 1 millisecond
 func Part2() (string, error) {
 	lines, err := shared.ReadFileLines("days/day5/input.txt")
 	if err != nil {
@@ -212,19 +357,551 @@ func Part2() (string, error) {
 		return "", err
 	}
 
-	lowestResult := -1
+	locations, err := almanac.computeLocationIntervals()
+	if err != nil {
+		return "", err
+	}
+
+	lowestLocation, _, err := findLowestLocation(locations)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(lowestLocation), nil
+}
+
+// Prints the exact set of locations that the seed ranges map to, and which
+// seed gives the lowest location
+func Ranges() (string, error) {
+	lines, err := shared.ReadFileLines("days/day5/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	almanac, err := readAlmanac(lines)
+	if err != nil {
+		return "", err
+	}
+
+	locations, err := almanac.computeLocationIntervals()
+	if err != nil {
+		return "", err
+	}
+
+	lowestLocation, seed, err := findLowestLocation(locations)
+	if err != nil {
+		return "", err
+	}
+
+	intervals := make([]interval.Interval, len(locations))
+	for i, t := range locations {
+		intervals[i] = t.values
+	}
+	locationSet := interval.NewIntervalSet(intervals...)
+
+	var sb strings.Builder
+	for _, in := range locationSet.Intervals() {
+		fmt.Fprintf(&sb, "%v\n", in)
+	}
+	fmt.Fprintf(&sb, "%d location intervals covering %d locations\n", len(locationSet.Intervals()), locationSet.Size())
+	fmt.Fprintf(&sb, "Lowest location %d comes from seed %d", lowestLocation, seed)
+	return sb.String(), nil
+}
+
+// Returns the relation that undoes every map of the chain, converting values
+// in the chain's final category back to its first category
+func invertChain(chain []CategoryMap) (interval.Relation, error) {
+	inverse := interval.IdentityRelation()
+	for i := len(chain) - 1; i >= 0; i-- {
+		m, err := chain[i].rangeMap.invert()
+		if err != nil {
+			return interval.Relation{}, err
+		}
+		inverse = inverse.Compose(m)
+	}
+	return inverse, nil
+}
+
+func (almanac Almanac) seedSet() interval.IntervalSet {
+	intervals := []interval.Interval{}
 	for _, seedRange := range almanac.seedRanges {
-		for seed := seedRange.start; seed < seedRange.start+seedRange.length; seed++ {
-			result, followingValues := computeSeedResult(seed, almanac)
-			if lowestResult == -1 || result < lowestResult {
-				lowestResult = result
+		intervals = append(intervals, interval.Interval{Start: seedRange.start, End: seedRange.start + seedRange.length})
+	}
+	return interval.NewIntervalSet(intervals...)
+}
+
+// Finds the lowest location by working upwards from location zero until one
+// maps back to a seed in the seed ranges. Rather than trying every location it
+// skips ahead to the next point where either the inverted maps change or one
+// of the seeds reaches a seed range.
+func (almanac Almanac) searchLowestLocation() (int, error) {
+	chain, err := almanac.findChain("seed", "location")
+	if err != nil {
+		return 0, err
+	}
+	inverse, err := invertChain(chain)
+	if err != nil {
+		return 0, err
+	}
+	seedSet := almanac.seedSet()
+
+	for location := 0; location < math.MaxInt; {
+		seeds, followingValues := inverse.Lookup(location)
+		skip := followingValues + 1
+		for _, seed := range seeds {
+			next, ok := seedSet.Next(seed)
+			if !ok {
+				continue
 			}
+			if next == seed {
+				return location, nil
+			}
+			skip = min(skip, next-seed)
+		}
 
-			// We're only looking for the lowest result, and the following values all map linearly
-			// so they must be higher than the result we just computed and we can ignore them
-			seed += followingValues
+		if location > math.MaxInt-skip {
+			break
 		}
+		location += skip
 	}
+	return 0, fmt.Errorf("no location maps back to a seed")
+}
 
-	return strconv.Itoa(lowestResult), nil
+// Finds which values in one category map into a range of values in another,
+// by default which seeds end up in a range of locations
+func Reverse(args []string) (string, error) {
+	if len(args) < 2 {
+		return "", fmt.Errorf("usage: reverse <start> <length> [--from <category>] [--to <category>]")
+	}
+	start, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "", err
+	}
+	length, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "", err
+	}
+
+	flags := flag.NewFlagSet("day5 reverse", flag.ContinueOnError)
+	from := flags.String("from", "location", "the category of the given range")
+	to := flags.String("to", "seed", "the category to convert back to")
+	if err := flags.Parse(args[2:]); err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day5/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	almanac, err := readAlmanac(lines)
+	if err != nil {
+		return "", err
+	}
+
+	chain, err := almanac.findChain(*to, *from)
+	if err != nil {
+		return "", err
+	}
+
+	inverse, err := invertChain(chain)
+	if err != nil {
+		return "", err
+	}
+
+	sources := inverse.Image(interval.NewIntervalSet(interval.Interval{Start: start, End: start + length}))
+
+	var sb strings.Builder
+	for _, in := range sources.Intervals() {
+		fmt.Fprintf(&sb, "%v\n", in)
+	}
+	fmt.Fprintf(&sb, "%d %s intervals covering %d values", len(sources.Intervals()), *to, sources.Size())
+	if *to == "seed" {
+		fmt.Fprintf(&sb, ", of which %d are in the seed ranges", sources.Intersect(almanac.seedSet()).Size())
+	}
+	return sb.String(), nil
+}
+
+// Checks the answer to part 2 by searching upwards through the locations
+func Check() (string, error) {
+	lines, err := shared.ReadFileLines("days/day5/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	almanac, err := readAlmanac(lines)
+	if err != nil {
+		return "", err
+	}
+
+	locations, err := almanac.computeLocationIntervals()
+	if err != nil {
+		return "", err
+	}
+
+	propagated, _, err := findLowestLocation(locations)
+	if err != nil {
+		return "", err
+	}
+
+	searched, err := almanac.searchLowestLocation()
+	if err != nil {
+		return "", err
+	}
+
+	if propagated != searched {
+		return "", fmt.Errorf("interval propagation gives %d but searching locations gives %d", propagated, searched)
+	}
+	return fmt.Sprintf("Both methods give a lowest location of %d", propagated), nil
+}
+
+// Returns a single map equivalent to applying every map of the chain in turn
+func composeChain(chain []CategoryMap) (interval.PiecewiseLinearMap, error) {
+	composed, err := interval.NewPiecewiseLinearMap(nil)
+	if err != nil {
+		return interval.PiecewiseLinearMap{}, err
+	}
+	for _, m := range chain {
+		next, err := m.rangeMap.toPiecewiseLinearMap()
+		if err != nil {
+			return interval.PiecewiseLinearMap{}, err
+		}
+		composed = composed.Compose(next)
+	}
+	return composed, nil
+}
+
+// Returns an interval outside of which every map in the chain leaves values unchanged
+func chainBounds(chain []CategoryMap) interval.Interval {
+	bounds := interval.Interval{Start: 0, End: 1}
+	for _, m := range chain {
+		for _, entry := range m.rangeMap.entries {
+			bounds.Start = min(bounds.Start, entry.sourceStart, entry.destinationStart)
+			bounds.End = max(bounds.End, entry.sourceStart+entry.length, entry.destinationStart+entry.length)
+		}
+	}
+	return bounds
+}
+
+// Checks that the composed map agrees with looking values up one map at a time.
+// Propagating the whole of chainBounds through the chain splits it into
+// intervals that each move by a constant offset, so it is enough to check that
+// the composed map moves each of them by the same offset and that step by
+// step lookups agree at both ends.
+func verifyComposedMap(chain []CategoryMap, composed interval.PiecewiseLinearMap) (int, error) {
+	bounds := chainBounds(chain)
+	tracked := []TrackedInterval{{values: bounds, seedStart: bounds.Start}}
+	for _, m := range chain {
+		var err error
+		tracked, err = propagateIntervals(tracked, m.rangeMap)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, t := range tracked {
+		source := interval.Interval{Start: t.seedStart, End: t.seedStart + t.values.Length()}
+		offset := t.values.Start - source.Start
+
+		parts := composed.Split(source)
+		if len(parts) != 1 || parts[0].Offset != offset {
+			return 0, fmt.Errorf("composed map does not move %v by %d", source, offset)
+		}
+
+		for _, x := range []int{source.Start, source.End - 1} {
+			if value := lookupChain(chain, x); value != x+offset {
+				return 0, fmt.Errorf("composed map sends %d to %d but looking it up gives %d", x, x+offset, value)
+			}
+		}
+	}
+	return len(tracked), nil
+}
+
+// Collapses a chain of maps into a single map and prints it in the same
+// format as the input
+func Compose(args []string) (string, error) {
+	flags := flag.NewFlagSet("day5 compose", flag.ContinueOnError)
+	from := flags.String("from", "seed", "the category to convert from")
+	to := flags.String("to", "location", "the category to convert to")
+	verify := flags.Bool("verify", false, "check the composed map against looking values up one map at a time")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day5/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	almanac, err := readAlmanac(lines)
+	if err != nil {
+		return "", err
+	}
+
+	chain, err := almanac.findChain(*from, *to)
+	if err != nil {
+		return "", err
+	}
+
+	composed, err := composeChain(chain)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s-to-%s map:\n", *from, *to)
+	for _, piece := range composed.Pieces() {
+		fmt.Fprintf(&sb, "%d %d %d\n", piece.Source.Start+piece.Offset, piece.Source.Start, piece.Source.Length())
+	}
+
+	if *verify {
+		numChecked, err := verifyComposedMap(chain, composed)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "\nVerified %d intervals against step by step lookup\n", numChecked)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// Looks up a single value, converting it from one category to another and
+// printing the value at each step along the way
+func Lookup(args []string) (string, error) {
+	if len(args) < 2 {
+		return "", fmt.Errorf("usage: lookup <category> <value> [--to <category>]")
+	}
+	from := args[0]
+	value, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "", err
+	}
+
+	flags := flag.NewFlagSet("day5 lookup", flag.ContinueOnError)
+	to := flags.String("to", "location", "the category to convert to")
+	if err := flags.Parse(args[2:]); err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day5/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	almanac, err := readAlmanac(lines)
+	if err != nil {
+		return "", err
+	}
+
+	chain, err := almanac.findChain(from, *to)
+	if err != nil {
+		return "", err
+	}
+
+	steps := []string{fmt.Sprintf("%s %d", from, value)}
+	for _, m := range chain {
+		value, _ = m.rangeMap.lookup(value)
+		steps = append(steps, fmt.Sprintf("%s %d", m.to, value))
+	}
+	return strings.Join(steps, " -> "), nil
+}
+
+// A map entry along with the line it was read from
+type numberedEntry struct {
+	line  int
+	entry RangeMapEntry
+}
+
+func validateSeeds(line string) []shared.ValidationIssue {
+	rest, ok := strings.CutPrefix(line, "seeds: ")
+	if !ok {
+		return []shared.ValidationIssue{shared.NewIssue(shared.Error, 1, "first line should list the seeds")}
+	}
+
+	issues := []shared.ValidationIssue{}
+	fields := strings.Fields(rest)
+	for _, field := range fields {
+		seed, err := strconv.Atoi(field)
+		if err != nil {
+			issues = append(issues, shared.NewIssue(shared.Error, 1, fmt.Sprintf("seed %q is not a number", field)))
+		} else if seed < 0 {
+			issues = append(issues, shared.NewIssue(shared.Error, 1, fmt.Sprintf("seed %d is negative", seed)))
+		}
+	}
+	if len(fields)%2 != 0 {
+		issues = append(issues, shared.NewIssue(shared.Error, 1, fmt.Sprintf("there are %d seed values so they can't be read as ranges", len(fields))))
+	}
+	return issues
+}
+
+// Parses an entry, returning an issue instead if it is malformed
+func validateEntry(line string, lineNumber int) (RangeMapEntry, []shared.ValidationIssue) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return RangeMapEntry{}, []shared.ValidationIssue{shared.NewIssue(shared.Error, lineNumber, fmt.Sprintf("expected 3 numbers but found %d values", len(fields)))}
+	}
+
+	values := make([]int, 3)
+	for i, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return RangeMapEntry{}, []shared.ValidationIssue{shared.NewIssue(shared.Error, lineNumber, fmt.Sprintf("%q is not a number", field))}
+		}
+		values[i] = value
+	}
+
+	entry := RangeMapEntry{destinationStart: values[0], sourceStart: values[1], length: values[2]}
+	issues := []shared.ValidationIssue{}
+	if entry.destinationStart < 0 || entry.sourceStart < 0 || entry.length < 0 {
+		issues = append(issues, shared.NewIssue(shared.Error, lineNumber, "entry contains a negative number"))
+	} else if entry.length == 0 {
+		issues = append(issues, shared.NewIssue(shared.Warning, lineNumber, "entry has zero length"))
+	}
+	return entry, issues
+}
+
+// Overlapping sources make the result depend on the order of the entries.
+// Overlapping destinations are allowed but mean the map can't be inverted.
+func findOverlaps(entries []numberedEntry) []shared.ValidationIssue {
+	issues := []shared.ValidationIssue{}
+	for i, a := range entries {
+		for _, b := range entries[:i] {
+			aSource := interval.Interval{Start: a.entry.sourceStart, End: a.entry.sourceStart + a.entry.length}
+			bSource := interval.Interval{Start: b.entry.sourceStart, End: b.entry.sourceStart + b.entry.length}
+			if aSource.Overlaps(bSource) {
+				issues = append(issues, shared.NewIssue(shared.Error, a.line, fmt.Sprintf("source range %v overlaps source range %v on line %d", aSource, bSource, b.line)))
+			}
+
+			aDestination := interval.Interval{Start: a.entry.destinationStart, End: a.entry.destinationStart + a.entry.length}
+			bDestination := interval.Interval{Start: b.entry.destinationStart, End: b.entry.destinationStart + b.entry.length}
+			if aDestination.Overlaps(bDestination) {
+				issues = append(issues, shared.NewIssue(shared.Warning, a.line, fmt.Sprintf("destination range %v overlaps destination range %v on line %d", aDestination, bDestination, b.line)))
+			}
+		}
+	}
+	return issues
+}
+
+// Checks the almanac line by line, so that it can report problems that
+// readAlmanac would either reject outright or silently accept
+func validateAlmanac(lines []string) []shared.ValidationIssue {
+	if len(lines) == 0 {
+		return []shared.ValidationIssue{shared.NewIssue(shared.Error, 1, "almanac is empty")}
+	}
+
+	issues := validateSeeds(lines[0])
+	if len(lines) > 1 && lines[1] != "" {
+		issues = append(issues, shared.NewIssue(shared.Error, 2, "expected a blank line after the seeds"))
+	}
+
+	seenMaps := map[string]int{}
+	row := 2
+	for row < len(lines) {
+		if lines[row] == "" {
+			row++
+			continue
+		}
+
+		header := row + 1
+		from, to, err := readMapName(lines[row])
+		if err != nil {
+			issues = append(issues, shared.NewIssue(shared.Error, header, err.Error()))
+		} else if previous, ok := seenMaps[from+"-to-"+to]; ok {
+			issues = append(issues, shared.NewIssue(shared.Error, header, fmt.Sprintf("map from %s to %s was already defined on line %d", from, to, previous)))
+		} else {
+			seenMaps[from+"-to-"+to] = header
+		}
+
+		entries := []numberedEntry{}
+		for row++; row < len(lines) && lines[row] != ""; row++ {
+			if _, _, err := readMapName(lines[row]); err == nil {
+				issues = append(issues, shared.NewIssue(shared.Error, row+1, "expected a blank line before the map header"))
+				break
+			}
+
+			entry, entryIssues := validateEntry(lines[row], row+1)
+			issues = append(issues, entryIssues...)
+			if len(entryIssues) == 0 || entryIssues[0].Severity == shared.Warning {
+				entries = append(entries, numberedEntry{row + 1, entry})
+			}
+		}
+		issues = append(issues, findOverlaps(entries)...)
+	}
+
+	// Only check the maps link up if they could all be read
+	if len(issues) == 0 {
+		if almanac, err := readAlmanac(lines); err != nil {
+			issues = append(issues, shared.NewIssue(shared.Error, 1, err.Error()))
+		} else if _, err := almanac.findChain("seed", "location"); err != nil {
+			issues = append(issues, shared.NewIssue(shared.Error, 1, err.Error()))
+		}
+	}
+
+	return issues
+}
+
+// Checks the almanac for problems and returns an error if there are any
+// errors, or any warnings when --strict is given.
+func Validate(args []string) (string, error) {
+	flags := flag.NewFlagSet("day5 validate", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day5/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	issues := validateAlmanac(lines)
+	return shared.ValidationReport(issues, fmt.Sprintf("%d lines", len(lines)), *strict)
+}
+
+// Generates an almanac with size seed ranges and seven maps of size entries each
+func Generate(r *rand.Rand, size int) []string {
+	const maxValue = 1 << 32
+
+	seeds := []string{}
+	for i := 0; i < size; i++ {
+		length := 1 + r.Intn(maxValue/(4*max(size, 1)))
+		start := r.Intn(maxValue - length)
+		seeds = append(seeds, strconv.Itoa(start), strconv.Itoa(length))
+	}
+	lines := []string{"seeds: " + strings.Join(seeds, " ")}
+
+	names := []string{"seed", "soil", "fertilizer", "water", "light", "temperature", "humidity", "location"}
+	for m := 0; m+1 < len(names); m++ {
+		lines = append(lines, "", fmt.Sprintf("%s-to-%s map:", names[m], names[m+1]))
+
+		// Choose non-overlapping source ranges with gaps between them
+		numEntries := max(size, 1)
+		boundaries := []int{}
+		for i := 0; i < 2*numEntries; i++ {
+			boundaries = append(boundaries, r.Intn(maxValue))
+		}
+		sort.Ints(boundaries)
+
+		entries := []RangeMapEntry{}
+		for i := 0; i+1 < len(boundaries); i += 2 {
+			if boundaries[i+1] > boundaries[i] {
+				entries = append(entries, RangeMapEntry{sourceStart: boundaries[i], length: boundaries[i+1] - boundaries[i]})
+			}
+		}
+		// Lay the destinations out end to end in a random order, so that no
+		// two entries map to the same value
+		total := 0
+		for _, e := range entries {
+			total += e.length
+		}
+		destination := r.Intn(maxValue - total + 1)
+		for _, i := range r.Perm(len(entries)) {
+			entries[i].destinationStart = destination
+			destination += entries[i].length
+		}
+
+		for _, e := range entries {
+			lines = append(lines, fmt.Sprintf("%d %d %d", e.destinationStart, e.sourceStart, e.length))
+		}
+	}
+	return lines
 }
@@ -1,11 +1,17 @@
 package day6
 
 import (
+	"encoding/csv"
+	"flag"
 	"fmt"
 	"math"
+	"math/big"
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
+	"robertbrignull/adventofcode2023/shared/numtheory"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 )
 
 func readIntFields(line string) ([]int, error) {
@@ -20,7 +26,9 @@ func readIntFields(line string) ([]int, error) {
 	return values, nil
 }
 
-func computeNumWaysToWin(time int, recordDistance int) (int, error) {
+// Counts how many hold times travel at least recordDistance, using floating point.
+// This loses precision once time*time is above 2^53.
+func computeNumWaysToWinFloat(time int, recordDistance int) (int, error) {
 	// r = h * (t - h) = h.t - h^2
 	// =>  h^2 - t.h + r = 0
 	// =>  (h - t/2)^2 - (t^2)/4 + r = 0
@@ -36,35 +44,300 @@ func computeNumWaysToWin(time int, recordDistance int) (int, error) {
 	return int(math.Floor(float64(time)/2+s)) - int(math.Ceil(float64(time)/2-s)) + 1, nil
 }
 
-// Time taken: 53 minutes
-func Part1() (string, error) {
-	lines, err := shared.ReadFileLines("days/day6/input.txt")
+// Same as computeNumWaysToWinFloat but using only integer arithmetic:
+//
+//	h * (t - h) >= r  <=>  (2h - t)^2 <= t^2 - 4r
+//
+// so with k = isqrt(t^2 - 4r) we need |2h - t| <= k. 2h - t always has the
+// same parity as t, so there are k + 1 solutions if k has the same parity as
+// t and k solutions otherwise.
+func computeNumWaysToWin(time int, recordDistance int) (int, error) {
+	timeSquared, ok := numtheory.MulChecked(time, time)
+	if !ok {
+		return 0, fmt.Errorf("time %d is too large, use computeNumWaysToWinBig instead", time)
+	}
+	fourR, ok := numtheory.MulChecked(4, recordDistance)
+	if !ok {
+		return 0, fmt.Errorf("distance %d is too large, use computeNumWaysToWinBig instead", recordDistance)
+	}
+	discriminant, ok := numtheory.SubChecked(timeSquared, fourR)
+	if !ok {
+		return 0, fmt.Errorf("distance %d is too large, use computeNumWaysToWinBig instead", recordDistance)
+	}
+	if discriminant < 0 {
+		return 0, fmt.Errorf("Unable to reach distance %d in time %d", recordDistance, time)
+	}
+
+	k := numtheory.ISqrt(discriminant)
+	if (k-time)%2 == 0 {
+		return k + 1, nil
+	}
+	return k, nil
+}
+
+// Same as computeNumWaysToWin but works for times and distances of any size
+func computeNumWaysToWinBig(time *big.Int, recordDistance *big.Int) (*big.Int, error) {
+	discriminant := new(big.Int).Mul(time, time)
+	discriminant.Sub(discriminant, new(big.Int).Lsh(recordDistance, 2))
+	if discriminant.Sign() < 0 {
+		return nil, fmt.Errorf("Unable to reach distance %s in time %s", recordDistance, time)
+	}
+
+	k := new(big.Int).Sqrt(discriminant)
+	if k.Bit(0) == time.Bit(0) {
+		k.Add(k, big.NewInt(1))
+	}
+	return k, nil
+}
+
+// Describes how a boat moves. Holding the button for h milliseconds raises
+// the boat's speed by acceleration per millisecond from initialSpeed, up to
+// maxSpeed, and the boat then travels at that speed for the rest of the race.
+// A maxSpeed of zero means there is no limit.
+type RaceModel struct {
+	acceleration int
+	maxSpeed     int
+	initialSpeed int
+}
+
+// The model used by the puzzle itself
+var defaultRaceModel = RaceModel{acceleration: 1}
+
+func (m RaceModel) speed(hold int) int {
+	speed := m.initialSpeed + m.acceleration*hold
+	if m.maxSpeed > 0 {
+		speed = min(speed, m.maxSpeed)
+	}
+	return speed
+}
+
+func (m RaceModel) distance(time int, hold int) int {
+	return m.speed(hold) * (time - hold)
+}
+
+// Returns the hold times at which the boat is still accelerating when released,
+// as an inclusive range that may be empty. All later hold times give the
+// boat the same speed.
+func (m RaceModel) acceleratingHoldTimes(time int) (int, int) {
+	if m.acceleration == 0 {
+		return 0, -1
+	}
+	if m.maxSpeed == 0 {
+		return 0, time
+	}
+	if m.initialSpeed >= m.maxSpeed {
+		return 0, -1
+	}
+	return 0, min((m.maxSpeed-m.initialSpeed)/m.acceleration, time)
+}
+
+// Checks that every value the model computes for this race fits in an int.
+// The largest are the square of the speed the boat would reach by holding for
+// the whole race, and four times the acceleration times the record.
+func (m RaceModel) checkRace(time int, record int) error {
+	if time < 0 {
+		return fmt.Errorf("time must not be negative: %d", time)
+	}
+	tooLarge := fmt.Errorf("race with time %d and record %d is too large for the race model", time, record)
+
+	at, ok := numtheory.MulChecked(m.acceleration, time)
+	if !ok {
+		return tooLarge
+	}
+	topSpeed, ok := numtheory.AddChecked(at, m.initialSpeed)
+	if !ok {
+		return tooLarge
+	}
+	if _, ok := numtheory.MulChecked(topSpeed, topSpeed); !ok {
+		return tooLarge
+	}
+	if _, ok := numtheory.MulChecked(topSpeed, time); !ok {
+		return tooLarge
+	}
+	need, ok := numtheory.AddChecked(record, 1)
+	if !ok {
+		return tooLarge
+	}
+	if _, ok := numtheory.MulChecked(4*m.acceleration, need); !ok {
+		return tooLarge
+	}
+	return nil
+}
+
+func floorDiv(a int, b int) int {
+	return (a - numtheory.Mod(a, b)) / b
+}
+
+func ceilDiv(a int, b int) int {
+	return -floorDiv(-a, b)
+}
+
+// Returns the first and last hold times that beat the record. The distance
+// travelled is concave in the hold time so these bound every winning hold time.
+//
+// While accelerating the distance is (v + a.h) * (t - h), and this beats the
+// record r when (2a.h - (a.t - v))^2 <= (a.t + v)^2 - 4a(r + 1). After that
+// the speed is constant and the distance falls linearly.
+func (m RaceModel) winningHoldTimes(time int, record int) (int, int, error) {
+	if err := m.checkRace(time, record); err != nil {
+		return 0, 0, err
+	}
+
+	first, last := time+1, -1
+	need := record + 1
+
+	accelStart, accelEnd := m.acceleratingHoldTimes(time)
+	if accelStart <= accelEnd {
+		a, v := m.acceleration, m.initialSpeed
+		b := a*time - v
+		discriminant := (a*time+v)*(a*time+v) - 4*a*need
+		if discriminant >= 0 {
+			k := numtheory.ISqrt(discriminant)
+			lo, hi := max(ceilDiv(b-k, 2*a), accelStart), min(floorDiv(b+k, 2*a), accelEnd)
+			if lo <= hi {
+				first, last = min(first, lo), max(last, hi)
+			}
+		}
+	}
+
+	if constantStart := accelEnd + 1; constantStart <= time {
+		speed := m.speed(constantStart)
+		if speed > 0 {
+			// A negative record lets the boat lose distance and still win, but
+			// it can never be held for longer than the race lasts
+			hi := min(time-ceilDiv(need, speed), time)
+			if constantStart <= hi {
+				first, last = min(first, constantStart), max(last, hi)
+			}
+		}
+	}
+
+	if first > last {
+		return 0, 0, fmt.Errorf("Unable to beat distance %d in time %d", record, time)
+	}
+	return first, last, nil
+}
+
+func (m RaceModel) numWaysToWin(time int, record int) (int, error) {
+	first, last, err := m.winningHoldTimes(time, record)
 	if err != nil {
-		return "", err
+		return 0, err
+	}
+	return last - first + 1, nil
+}
+
+// Returns the hold time that travels furthest, preferring shorter hold times.
+// The best hold time is either next to the peak of the accelerating parabola
+// or at one end of a phase, so only those need checking. The race must have
+// passed checkRace.
+func (m RaceModel) optimalHoldTime(time int) int {
+	candidates := []int{0, time}
+	accelStart, accelEnd := m.acceleratingHoldTimes(time)
+	if accelStart <= accelEnd {
+		peak := floorDiv(m.acceleration*time-m.initialSpeed, 2*m.acceleration)
+		for _, h := range []int{peak, peak + 1, accelEnd, accelEnd + 1} {
+			candidates = append(candidates, min(max(h, 0), time))
+		}
+	}
+
+	best := candidates[0]
+	for _, h := range candidates {
+		d, bestD := m.distance(time, h), m.distance(time, best)
+		if d > bestD || (d == bestD && h < best) {
+			best = h
+		}
+	}
+	return best
+}
+
+// Reads the times and record distances of the individual races
+func readRaces(lines []string) ([]int, []int, error) {
+	if len(lines) < 2 {
+		return nil, nil, fmt.Errorf("expected a line of times and a line of distances")
 	}
 
 	times, err := readIntFields(lines[0])
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
 	distances, err := readIntFields(lines[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(times) != len(distances) {
+		return nil, nil, fmt.Errorf("there are %d times but %d distances", len(times), len(distances))
+	}
+	return times, distances, nil
+}
+
+// Time taken: 53 minutes
+func Part1() (string, error) {
+	lines, err := shared.ReadFileLines("days/day6/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	times, distances, err := readRaces(lines)
 	if err != nil {
 		return "", err
 	}
 
+	// Switch to big arithmetic once the product no longer fits in an int
 	result := 1
+	var bigResult *big.Int
 	for i := range times {
-		r, err := computeNumWaysToWin(times[i], distances[i]+1)
+		r, err := defaultRaceModel.numWaysToWin(times[i], distances[i])
 		if err != nil {
 			return "", err
 		}
-		result *= r
+		if bigResult != nil {
+			bigResult.Mul(bigResult, big.NewInt(int64(r)))
+			continue
+		}
+		product, ok := numtheory.MulChecked(result, r)
+		if !ok {
+			bigResult = new(big.Int).Mul(big.NewInt(int64(result)), big.NewInt(int64(r)))
+			continue
+		}
+		result = product
 	}
 
+	if bigResult != nil {
+		return bigResult.String(), nil
+	}
 	return strconv.Itoa(result), nil
 }
 
+// Reads a line of numbers as though the spaces between them weren't there
+func readConcatenatedField(line string) (*big.Int, error) {
+	_, str, _ := strings.Cut(line, ":")
+	value, ok := new(big.Int).SetString(strings.Replace(str, " ", "", -1), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %s", line)
+	}
+	return value, nil
+}
+
+// Reads the single race from part 2, where the spaces between numbers are ignored
+func readCombinedRace(lines []string) (*big.Int, *big.Int, error) {
+	if len(lines) < 2 {
+		return nil, nil, fmt.Errorf("expected a line of times and a line of distances")
+	}
+
+	time, err := readConcatenatedField(lines[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	distance, err := readConcatenatedField(lines[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return time, distance, nil
+}
+
 // Time taken: 4 minutes
 func Part2() (string, error) {
 	lines, err := shared.ReadFileLines("days/day6/input.txt")
@@ -72,20 +345,356 @@ func Part2() (string, error) {
 		return "", err
 	}
 
-	time, err := strconv.Atoi(strings.Replace(lines[0][len("Time:"):], " ", "", -1))
+	time, distance, err := readCombinedRace(lines)
+	if err != nil {
+		return "", err
+	}
+
+	if time.IsInt64() && distance.IsInt64() {
+		t, d := int(time.Int64()), int(distance.Int64())
+		if defaultRaceModel.checkRace(t, d) == nil {
+			result, err := defaultRaceModel.numWaysToWin(t, d)
+			if err != nil {
+				return "", err
+			}
+			return strconv.Itoa(result), nil
+		}
+	}
+
+	// Too large for the race model, but the default model can be solved with math/big
+	result, err := computeNumWaysToWinBig(time, distance.Add(distance, big.NewInt(1)))
+	if err != nil {
+		return "", err
+	}
+
+	return result.String(), nil
+}
+
+// Solves every race of both parts using the float, integer and math/big
+// methods, and checks that they agree
+func Check() (string, error) {
+	lines, err := shared.ReadFileLines("days/day6/input.txt")
 	if err != nil {
 		return "", err
 	}
 
-	distance, err := strconv.Atoi(strings.Replace(lines[1][len("Distance:"):], " ", "", -1))
+	times, distances, err := readRaces(lines)
 	if err != nil {
 		return "", err
 	}
 
-	result, err := computeNumWaysToWin(time, distance+1)
+	// Part 2 is checked as one more race, if it fits in an int
+	time, distance, err := readCombinedRace(lines)
 	if err != nil {
 		return "", err
 	}
+	if time.IsInt64() && distance.IsInt64() {
+		times = append(times, int(time.Int64()))
+		distances = append(distances, int(distance.Int64()))
+	}
 
-	return strconv.Itoa(result), nil
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "Time\tDistance\tFloat\tInteger\tBig\t\n")
+	numDisagreements := 0
+	for i := range times {
+		row := []string{strconv.Itoa(times[i]), strconv.Itoa(distances[i])}
+		results := []string{}
+
+		if r, err := computeNumWaysToWinFloat(times[i], distances[i]+1); err != nil {
+			results = append(results, "error")
+		} else {
+			results = append(results, strconv.Itoa(r))
+		}
+
+		if r, err := computeNumWaysToWin(times[i], distances[i]+1); err != nil {
+			results = append(results, "error")
+		} else {
+			results = append(results, strconv.Itoa(r))
+		}
+
+		if r, err := computeNumWaysToWinBig(big.NewInt(int64(times[i])), big.NewInt(int64(distances[i]+1))); err != nil {
+			results = append(results, "error")
+		} else {
+			results = append(results, r.String())
+		}
+
+		if results[0] != results[1] || results[1] != results[2] {
+			numDisagreements++
+		}
+		fmt.Fprintf(w, "%s\t\n", strings.Join(append(row, results...), "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+
+	if numDisagreements > 0 {
+		return "", fmt.Errorf("%smethods disagree on %d races", sb.String(), numDisagreements)
+	}
+	fmt.Fprintf(&sb, "All methods agree on %d races", len(times))
+	return sb.String(), nil
+}
+
+type raceModelFlags struct {
+	acceleration *int
+	maxSpeed     *int
+	initialSpeed *int
+}
+
+// Registers the flags that describe a race model, defaulting to the puzzle's own model
+func addRaceModelFlags(flags *flag.FlagSet) raceModelFlags {
+	return raceModelFlags{
+		acceleration: flags.Int("acceleration", defaultRaceModel.acceleration, "speed gained per millisecond the button is held"),
+		maxSpeed:     flags.Int("max-speed", defaultRaceModel.maxSpeed, "the fastest the boat can go, or 0 for no limit"),
+		initialSpeed: flags.Int("initial-speed", defaultRaceModel.initialSpeed, "the speed of the boat before the button is held"),
+	}
+}
+
+func (rf raceModelFlags) buildModel() (RaceModel, error) {
+	if *rf.acceleration < 0 || *rf.maxSpeed < 0 || *rf.initialSpeed < 0 {
+		return RaceModel{}, fmt.Errorf("acceleration and speeds must not be negative")
+	}
+	return RaceModel{*rf.acceleration, *rf.maxSpeed, *rf.initialSpeed}, nil
+}
+
+// Solves each race, and the concatenated race from part 2, under a race
+// model given by the flags
+func Model(args []string) (string, error) {
+	flags := flag.NewFlagSet("day6 model", flag.ContinueOnError)
+	modelFlags := addRaceModelFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	model, err := modelFlags.buildModel()
+	if err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day6/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	times, distances, err := readRaces(lines)
+	if err != nil {
+		return "", err
+	}
+
+	time, distance, err := readCombinedRace(lines)
+	if err != nil {
+		return "", err
+	}
+	if !time.IsInt64() || !distance.IsInt64() {
+		return "", fmt.Errorf("part 2 race is too large for the race model")
+	}
+	times = append(times, int(time.Int64()))
+	distances = append(distances, int(distance.Int64()))
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "Race\tTime\tRecord\tWays to win\tBest hold\tBest distance\t\n")
+	product := 1
+	for i := range times {
+		name := strconv.Itoa(i + 1)
+		if i == len(times)-1 {
+			name = "Part 2"
+		}
+
+		if err := model.checkRace(times[i], distances[i]); err != nil {
+			return "", err
+		}
+
+		// The only other error is that the record can't be beaten
+		ways, err := model.numWaysToWin(times[i], distances[i])
+		if err != nil {
+			ways = 0
+		}
+		if i < len(times)-1 {
+			var ok bool
+			product, ok = numtheory.MulChecked(product, ways)
+			if !ok {
+				return "", fmt.Errorf("product of ways to win overflows int")
+			}
+		}
+
+		hold := model.optimalHoldTime(times[i])
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t\n", name, times[i], distances[i], ways, hold, model.distance(times[i], hold))
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&sb, "Product of ways to win: %d", product)
+	return sb.String(), nil
+}
+
+// Draws the distance travelled for each hold time as a scatter plot, with
+// the record marked as a horizontal line
+func plotRace(model RaceModel, time int, record int) string {
+	const height = 16
+	const maxWidth = 100
+
+	// Sample the hold times evenly if there are too many to fit
+	holds := []int{}
+	for i := 0; i < min(time+1, maxWidth); i++ {
+		holds = append(holds, i*time/max(min(time, maxWidth-1), 1))
+	}
+
+	top := max(record, 1)
+	for _, h := range holds {
+		top = max(top, model.distance(time, h))
+	}
+	row := func(distance int) int {
+		return min(max(distance, 0)*(height-1)/top, height-1)
+	}
+
+	var sb strings.Builder
+	labelWidth := len(strconv.Itoa(top))
+	for y := height - 1; y >= 0; y-- {
+		label := ""
+		if y == height-1 {
+			label = strconv.Itoa(top)
+		} else if y == 0 {
+			label = "0"
+		}
+		fmt.Fprintf(&sb, "%*s |", labelWidth, label)
+		for _, h := range holds {
+			distance := model.distance(time, h)
+			if row(distance) == y && distance > record {
+				sb.WriteByte('#')
+			} else if row(distance) == y {
+				sb.WriteByte('*')
+			} else if row(record) == y {
+				sb.WriteByte('-')
+			} else {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	fmt.Fprintf(&sb, "%*s +%s\n", labelWidth, "", strings.Repeat("-", len(holds)))
+	fmt.Fprintf(&sb, "%*s  0%*d\n", labelWidth, "", len(holds)-1, time)
+	return sb.String()
+}
+
+// Lists every hold time for each race along with the distance it gets and
+// the margin over the record, then summarises the winning hold times
+func Table(args []string) (string, error) {
+	flags := flag.NewFlagSet("day6 table", flag.ContinueOnError)
+	modelFlags := addRaceModelFlags(flags)
+	race := flags.Int("race", 0, "only show this race, numbered from 1")
+	asCSV := flags.Bool("csv", false, "output CSV instead of a table")
+	plot := flags.Bool("plot", false, "draw a plot of the distance for each hold time")
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+	if *asCSV && *plot {
+		return "", fmt.Errorf("--plot can't be used with --csv")
+	}
+	model, err := modelFlags.buildModel()
+	if err != nil {
+		return "", err
+	}
+
+	lines, err := shared.ReadFileLines("days/day6/input.txt")
+	if err != nil {
+		return "", err
+	}
+
+	times, distances, err := readRaces(lines)
+	if err != nil {
+		return "", err
+	}
+
+	races := []int{}
+	for i := range times {
+		if *race == 0 || *race == i+1 {
+			races = append(races, i)
+		}
+	}
+	if len(races) == 0 {
+		return "", fmt.Errorf("there is no race %d", *race)
+	}
+	for _, i := range races {
+		if err := model.checkRace(times[i], distances[i]); err != nil {
+			return "", err
+		}
+	}
+
+	var sb strings.Builder
+	if *asCSV {
+		w := csv.NewWriter(&sb)
+		w.Write([]string{"race", "hold", "distance", "margin", "wins"})
+		for _, i := range races {
+			for h := 0; h <= times[i]; h++ {
+				distance := model.distance(times[i], h)
+				w.Write([]string{
+					strconv.Itoa(i + 1),
+					strconv.Itoa(h),
+					strconv.Itoa(distance),
+					strconv.Itoa(distance - distances[i]),
+					strconv.FormatBool(distance > distances[i]),
+				})
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(sb.String(), "\n"), nil
+	}
+
+	for n, i := range races {
+		if n > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "Race %d: time %d, record %d\n", i+1, times[i], distances[i])
+
+		w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(w, "Hold\tDistance\tMargin\t\t\n")
+		for h := 0; h <= times[i]; h++ {
+			distance := model.distance(times[i], h)
+			win := ""
+			if distance > distances[i] {
+				win = "win"
+			}
+			fmt.Fprintf(w, "%d\t%d\t%+d\t%s\t\n", h, distance, distance-distances[i], win)
+		}
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+
+		first, last, err := model.winningHoldTimes(times[i], distances[i])
+		if err != nil {
+			sb.WriteString("No hold time beats the record\n")
+		} else {
+			fmt.Fprintf(&sb, "Winning hold times: %d to %d (%d ways)\n", first, last, last-first+1)
+		}
+		hold := model.optimalHoldTime(times[i])
+		best := model.distance(times[i], hold)
+		fmt.Fprintf(&sb, "Best hold time: %d, distance %d, margin %+d\n", hold, best, best-distances[i])
+
+		if *plot {
+			sb.WriteString("\n" + plotRace(model, times[i], distances[i]))
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// Generates size races, each with a record that can be beaten
+func Generate(r *rand.Rand, size int) []string {
+	times := make([]string, size)
+	distances := make([]string, size)
+	for i := 0; i < size; i++ {
+		time := 7 + r.Intn(93)
+		bestDistance := (time / 2) * (time - time/2)
+		distance := r.Intn(bestDistance)
+
+		width := max(len(strconv.Itoa(time)), len(strconv.Itoa(distance)))
+		times[i] = fmt.Sprintf("%*d", width, time)
+		distances[i] = fmt.Sprintf("%*d", width, distance)
+	}
+	return []string{
+		"Time:      " + strings.Join(times, "   "),
+		"Distance:  " + strings.Join(distances, "   "),
+	}
 }
@@ -2,6 +2,7 @@ package day7
 
 import (
 	"fmt"
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
 	"sort"
 	"strconv"
@@ -313,3 +314,19 @@ func Part2() (string, error) {
 
 	return strconv.Itoa(winnings), nil
 }
+
+// Generates size hands of five cards, each with a bid
+func Generate(r *rand.Rand, size int) []string {
+	cards := "AKQJT98765432"
+	lines := make([]string, size)
+	for i := range lines {
+		hand := make([]byte, 5)
+		// Draw from a small subset of cards sometimes, to get more pairs and better
+		subset := 2 + r.Intn(len(cards)-1)
+		for j := range hand {
+			hand[j] = cards[r.Intn(subset)]
+		}
+		lines[i] = fmt.Sprintf("%s %d", hand, 1+r.Intn(1000))
+	}
+	return lines
+}
@@ -1,10 +1,14 @@
 package day8
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
+	"math/rand"
 	"regexp"
 	"robertbrignull/adventofcode2023/shared"
-	"sort"
+	"robertbrignull/adventofcode2023/shared/containers"
+	"robertbrignull/adventofcode2023/shared/numtheory"
 	"strconv"
 )
 
@@ -99,11 +103,11 @@ func (d DFA) backwardsTransitions() (map[string][]string, error) {
 }
 
 func (d DFA) computeStepsToDest() error {
-	keysToProcess := make([]string, 0)
+	keysToProcess := containers.NewDeque[string]()
 	for _, destNode := range d.destNodes {
 		for index := 0; index < d.numIndexes; index++ {
 			key := d.key(destNode, index)
-			keysToProcess = append(keysToProcess, key)
+			keysToProcess.PushBack(key)
 
 			t := d.transitions[key]
 			t.nextDestNode = destNode
@@ -118,11 +122,10 @@ func (d DFA) computeStepsToDest() error {
 	}
 
 	for {
-		if len(keysToProcess) == 0 {
+		key, ok := keysToProcess.PopFront()
+		if !ok {
 			return nil
 		}
-		key := keysToProcess[0]
-		keysToProcess = keysToProcess[1:]
 
 		transition := d.transitions[key]
 
@@ -133,7 +136,7 @@ func (d DFA) computeStepsToDest() error {
 				if prevTransition.stepsToDest == -1 {
 					prevTransition.stepsToDest = transition.stepsToDest + 1
 					prevTransition.nextDestNode = transition.nextDestNode
-					keysToProcess = append(keysToProcess, prevKey)
+					keysToProcess.PushBack(prevKey)
 					d.transitions[prevKey] = prevTransition
 				}
 			}
@@ -241,46 +244,201 @@ func Part1() (string, error) {
 	return strconv.Itoa(result.stepsToDest), nil
 }
 
-type GhostNode struct {
-	node  string
-	steps int
-	index int
+// Describes the times at which a single ghost is standing on a destination node.
+// Before the ghost enters its cycle it may pass through destination nodes at the
+// times listed in prefixHits. Once in the cycle, it is on a destination node at
+// every time t >= cycleStart where t is congruent to one of cycleHits modulo cycleLength.
+type GhostCycle struct {
+	prefixHits  []int
+	cycleStart  int
+	cycleLength int
+	cycleHits   []int
+}
+
+func findGhostCycle(instructions []Instruction, bs Branches, start string) (GhostCycle, error) {
+	type state struct {
+		node  string
+		index int
+	}
+
+	seen := make(map[state]int)
+	hits := []int{}
+	s := state{start, 0}
+	for steps := 0; ; steps++ {
+		if firstSeen, ok := seen[s]; ok {
+			gc := GhostCycle{cycleStart: firstSeen, cycleLength: steps - firstSeen}
+			for _, hit := range hits {
+				if hit < firstSeen {
+					gc.prefixHits = append(gc.prefixHits, hit)
+				} else {
+					gc.cycleHits = append(gc.cycleHits, hit)
+				}
+			}
+			return gc.simplify(), nil
+		}
+		seen[s] = steps
+
+		if s.node[2] == 'Z' {
+			hits = append(hits, steps)
+		}
+
+		nextNode, err := getNextNode(instructions, bs, s.node, s.index)
+		if err != nil {
+			return GhostCycle{}, err
+		}
+		s = state{nextNode, (s.index + 1) % len(instructions)}
+	}
+}
+
+// If the hits within the cycle are evenly spaced, so that they are every
+// value congruent to the first hit modulo some divisor of the cycle length,
+// then they can be replaced by that single congruence. This avoids trying
+// every combination of hits when the cycle is much longer than the gap
+// between destinations.
+func (gc GhostCycle) simplify() GhostCycle {
+	if len(gc.cycleHits) <= 1 {
+		return gc
+	}
+
+	d := gc.cycleLength
+	for _, hit := range gc.cycleHits {
+		d = numtheory.GCD(d, hit-gc.cycleHits[0])
+	}
+	if len(gc.cycleHits) == gc.cycleLength/d {
+		gc.cycleLength = d
+		gc.cycleHits = gc.cycleHits[:1]
+	}
+	return gc
+}
+
+func (gc GhostCycle) isOnDestAt(steps int) bool {
+	for _, hit := range gc.prefixHits {
+		if hit == steps {
+			return true
+		}
+	}
+	if steps < gc.cycleStart {
+		return false
+	}
+	for _, hit := range gc.cycleHits {
+		if (steps-hit)%gc.cycleLength == 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func countGhostStepsToDestination(instructions []Instruction, bs Branches) (int, error) {
-	nodes := []GhostNode{}
+	cycles := []GhostCycle{}
 	for node := range bs {
 		if node[2] == 'A' {
-			nodes = append(nodes, GhostNode{node: node, steps: 0, index: 0})
+			gc, err := findGhostCycle(instructions, bs, node)
+			if err != nil {
+				return 0, err
+			}
+			cycles = append(cycles, gc)
 		}
 	}
-
-	dfa, err := buildDFA(instructions, bs, false)
-	if err != nil {
-		return 0, err
+	if len(cycles) == 0 {
+		return 0, fmt.Errorf("No ghost start nodes found")
 	}
 
-	for {
-		sort.Slice(nodes, func(i, j int) bool {
-			return nodes[i].steps < nodes[j].steps
-		})
+	best := -1
 
-		if nodes[0].steps == nodes[len(nodes)-1].steps && nodes[0].steps != 0 {
-			return nodes[0].steps, nil
+	// Check whether all ghosts meet before one of them has entered its cycle
+	for _, gc := range cycles {
+		for _, hit := range gc.prefixHits {
+			if best != -1 && hit >= best {
+				continue
+			}
+			allOnDest := true
+			for _, other := range cycles {
+				if !other.isOnDestAt(hit) {
+					allOnDest = false
+					break
+				}
+			}
+			if allOnDest {
+				best = hit
+			}
 		}
+	}
+
+	// Otherwise every ghost is in its cycle, so try each combination of cycle hits
+	cycleStart := 0
+	moduli := make([]int, len(cycles))
+	for i, gc := range cycles {
+		cycleStart = max(cycleStart, gc.cycleStart)
+		moduli[i] = gc.cycleLength
+	}
 
-		result := dfa.transitions[dfa.key(nodes[0].node, nodes[0].index)]
-		if result.stepsToDest == -1 {
-			return 0, fmt.Errorf("No route from %s to dest node", nodes[0].node)
+	// Combinations whose first meeting doesn't fit in an int can't be the
+	// answer, but still show that the ghosts do meet
+	tooLarge := false
+	residues := make([]int, len(cycles))
+	var search func(i int)
+	search = func(i int) {
+		if i == len(cycles) {
+			x, ok := firstMeeting(residues, moduli, cycleStart)
+			if !ok {
+				tooLarge = true
+			} else if x >= 0 && (best == -1 || x < best) {
+				best = x
+			}
+			return
+		}
+		for _, hit := range cycles[i].cycleHits {
+			residues[i] = hit
+			search(i + 1)
 		}
+	}
+	search(0)
+
+	if best == -1 && tooLarge {
+		return 0, fmt.Errorf("Ghosts all reach a destination node at the same time, but only after more steps than fit in an int")
+	}
+	if best == -1 {
+		return 0, fmt.Errorf("Ghosts never all reach a destination node at the same time")
+	}
+	return best, nil
+}
 
-		fmt.Printf("Advanced %s at step %d to %s at step %d\n", nodes[0].node, nodes[0].steps, result.nextDestNode, nodes[0].steps+result.stepsToDest)
-		nodes[0] = GhostNode{
-			node:  result.nextDestNode,
-			steps: nodes[0].steps + result.stepsToDest,
-			index: result.index,
+// Returns the first step at or after cycleStart where x = residues[i] (mod
+// moduli[i]) for all i, or -1 if there is no such step. ok is false if the
+// step exists but doesn't fit in an int.
+func firstMeeting(residues []int, moduli []int, cycleStart int) (int, bool) {
+	x, m, err := numtheory.CRT(residues, moduli)
+	if errors.Is(err, numtheory.ErrNoSolution) {
+		return -1, true
+	}
+	if err == nil {
+		if x >= cycleStart {
+			return x, true
+		}
+		// Round the gap up to a whole number of cycles
+		step, ok := numtheory.MulChecked((cycleStart-x-1)/m+1, m)
+		if ok {
+			if x, ok := numtheory.AddChecked(x, step); ok {
+				return x, true
+			}
 		}
 	}
+
+	bx, bm, ok := numtheory.CRTBig(residues, moduli)
+	if !ok {
+		return -1, true
+	}
+	start := big.NewInt(int64(cycleStart))
+	if bx.Cmp(start) < 0 {
+		// Round the gap up to a whole number of cycles
+		k := new(big.Int).Sub(start, bx)
+		k.Add(k, bm).Sub(k, big.NewInt(1)).Div(k, bm)
+		bx.Add(bx, k.Mul(k, bm))
+	}
+	if !bx.IsInt64() {
+		return 0, false
+	}
+	return int(bx.Int64()), true
 }
 
 // Time taken: 2h 01m and I think confirmed unfinishable with my problem input :(
@@ -307,3 +465,81 @@ func Part2() (string, error) {
 
 	return strconv.Itoa(steps), nil
 }
+
+// Generates instructions and a network of about size nodes, made up of six
+// separate cycles. Each cycle is entered from a start node ending in A and
+// passes through a single destination node ending in Z. Between the two, the
+// cycle is split into two lanes and every node has one branch into each lane,
+// so whichever way the instructions go a ghost moves exactly one step around
+// its cycle. This guarantees every start reaches its destination, and that the
+// ghosts all reach their destinations together after the LCM of the cycle
+// lengths. The first cycle goes from AAA to ZZZ.
+func Generate(r *rand.Rand, size int) []string {
+	instructions := make([]byte, 50+r.Intn(250))
+	for i := range instructions {
+		instructions[i] = "LR"[r.Intn(2)]
+	}
+
+	used := map[string]bool{"AAA": true, "ZZZ": true}
+	letters := "BCDEFGHIJKLMNOPQRSTUVWXY"
+	newName := func(last byte) string {
+		for {
+			name := string([]byte{byte('A' + r.Intn(26)), byte('A' + r.Intn(26)), last})
+			if !used[name] {
+				used[name] = true
+				return name
+			}
+		}
+	}
+
+	const numCycles = 6
+	network := []string{}
+	addNode := func(node string, a string, b string) {
+		if r.Intn(2) == 0 {
+			a, b = b, a
+		}
+		network = append(network, fmt.Sprintf("%s = (%s, %s)", node, a, b))
+	}
+
+	for c := 0; c < numCycles; c++ {
+		// Each cycle has a destination node plus two lanes of cycleLength-1 nodes
+		cycleLength := max(2, size/(2*numCycles)+r.Intn(size/(4*numCycles)+1))
+
+		start, dest := "AAA", "ZZZ"
+		if c > 0 {
+			start, dest = newName('A'), newName('Z')
+		}
+
+		lanes := [2][]string{}
+		for l := range lanes {
+			lanes[l] = make([]string, cycleLength-1)
+			for i := range lanes[l] {
+				lanes[l][i] = newName(letters[r.Intn(len(letters))])
+			}
+		}
+
+		// Returns the two nodes at position i of the cycle, where position 0 is the destination
+		nodesAt := func(i int) (string, string) {
+			i %= cycleLength
+			if i == 0 {
+				return dest, dest
+			}
+			return lanes[0][i-1], lanes[1][i-1]
+		}
+
+		a, b := nodesAt(1)
+		addNode(start, a, b)
+		addNode(dest, a, b)
+		for i := 1; i < cycleLength; i++ {
+			a, b := nodesAt(i + 1)
+			addNode(lanes[0][i-1], a, b)
+			addNode(lanes[1][i-1], a, b)
+		}
+	}
+
+	// Shuffle the network so the cycles aren't obvious
+	r.Shuffle(len(network), func(i, j int) {
+		network[i], network[j] = network[j], network[i]
+	})
+	return append([]string{string(instructions), ""}, network...)
+}
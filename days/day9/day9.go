@@ -1,6 +1,7 @@
 package day9
 
 import (
+	"math/rand"
 	"robertbrignull/adventofcode2023/shared"
 	"strconv"
 	"strings"
@@ -18,18 +19,6 @@ func readSequence(line string) ([]int, error) {
 	return xs, nil
 }
 
-func readSequences(lines []string) ([][]int, error) {
-	ss := make([][]int, 0)
-	for _, line := range lines {
-		s, err := readSequence(line)
-		if err != nil {
-			return ss, err
-		}
-		ss = append(ss, s)
-	}
-	return ss, nil
-}
-
 func computeDeltas(xs []int) []int {
 	ds := make([]int, len(xs)-1)
 	for i := 0; i < len(xs)-1; i++ {
@@ -70,16 +59,18 @@ func Part1() (string, error) {
 		return "", err
 	}
 
-	sequences, err := readSequences(lines)
+	total, err := shared.ParallelSum(lines, func(line string) (int, error) {
+		sequence, err := readSequence(line)
+		if err != nil {
+			return 0, err
+		}
+
+		return nextValueInSequence(sequence), nil
+	})
 	if err != nil {
 		return "", err
 	}
 
-	total := 0
-	for _, sequence := range sequences {
-		total += nextValueInSequence(sequence)
-	}
-
 	return strconv.Itoa(total), nil
 }
 
@@ -90,15 +81,44 @@ func Part2() (string, error) {
 		return "", err
 	}
 
-	sequences, err := readSequences(lines)
+	total, err := shared.ParallelSum(lines, func(line string) (int, error) {
+		sequence, err := readSequence(line)
+		if err != nil {
+			return 0, err
+		}
+
+		return prevValueInSequence(sequence), nil
+	})
 	if err != nil {
 		return "", err
 	}
 
-	total := 0
-	for _, sequence := range sequences {
-		total += prevValueInSequence(sequence)
-	}
-
 	return strconv.Itoa(total), nil
 }
+
+// Generates size sequences of 21 values, each given by a polynomial of degree
+// at most five so that the differences eventually become all zeros
+func Generate(r *rand.Rand, size int) []string {
+	lines := make([]string, size)
+	for i := range lines {
+		// Express the polynomial as a sum of binomial coefficients so that
+		// the values are always integers
+		degree := r.Intn(6)
+		coefficients := make([]int, degree+1)
+		for k := range coefficients {
+			coefficients[k] = r.Intn(11) - 5
+		}
+
+		values := make([]string, 21)
+		for n := range values {
+			v, binomial := 0, 1
+			for k, c := range coefficients {
+				v += c * binomial
+				binomial = binomial * (n - k) / (k + 1)
+			}
+			values[n] = strconv.Itoa(v)
+		}
+		lines[i] = strings.Join(values, " ")
+	}
+	return lines
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"robertbrignull/adventofcode2023/days/day1"
 	"robertbrignull/adventofcode2023/days/day10"
@@ -16,20 +18,86 @@ import (
 	"robertbrignull/adventofcode2023/days/day7"
 	"robertbrignull/adventofcode2023/days/day8"
 	"robertbrignull/adventofcode2023/days/day9"
+	"strings"
 )
 
+// Generates a random input for a day, deterministic for a given seed
+func generate(args []string) (string, error) {
+	if len(args) < 1 {
+		return "", fmt.Errorf("Usage: ./run gen <day> [--seed S] [--size N]")
+	}
+	day := args[0]
+
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	seed := flags.Int64("seed", 1, "random seed")
+	size := flags.Int("size", 100, "size of the input, usually the number of lines")
+	if err := flags.Parse(args[1:]); err != nil {
+		return "", err
+	}
+	if *size < 0 {
+		return "", fmt.Errorf("size must not be negative: %d", *size)
+	}
+
+	r := rand.New(rand.NewSource(*seed))
+
+	var lines []string
+	if day == "1" {
+		lines = day1.Generate(r, *size)
+	} else if day == "2" {
+		lines = day2.Generate(r, *size)
+	} else if day == "3" {
+		lines = day3.Generate(r, *size)
+	} else if day == "4" {
+		lines = day4.Generate(r, *size)
+	} else if day == "5" {
+		lines = day5.Generate(r, *size)
+	} else if day == "6" {
+		lines = day6.Generate(r, *size)
+	} else if day == "7" {
+		lines = day7.Generate(r, *size)
+	} else if day == "8" {
+		lines = day8.Generate(r, *size)
+	} else if day == "9" {
+		lines = day9.Generate(r, *size)
+	} else if day == "10" {
+		var err error
+		lines, err = day10.Generate(r, *size)
+		if err != nil {
+			return "", err
+		}
+	} else if day == "11" {
+		lines = day11.Generate(r, *size)
+	} else if day == "12" {
+		lines = day12.Generate(r, *size)
+	} else {
+		return "", fmt.Errorf("Unrecognised day: %s", day)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
 func main() {
 	args := os.Args[1:]
 
 	if len(args) > 0 && args[0] == "--" {
 		args = args[1:]
 	}
+
+	if len(args) > 0 && args[0] == "gen" {
+		result, err := generate(args[1:])
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		fmt.Printf("%s\n", result)
+		return
+	}
 	if len(args) < 2 {
-		log.Fatal("Usage: ./run <day> <part>\n")
+		log.Fatal("Usage: ./run <day> <part> [options]\n       ./run gen <day> [--seed S] [--size N]\n")
 	}
 
 	day := args[0]
 	part := args[1]
+	extraArgs := args[2:]
 
 	var result string
 	var err error
@@ -37,27 +105,57 @@ func main() {
 	if day == "1" && part == "1" {
 		result, err = day1.Part1()
 	} else if day == "1" && part == "2" {
-		result, err = day1.Part2()
+		result, err = day1.Part2(extraArgs)
+	} else if day == "1" && part == "explain" {
+		result, err = day1.Explain(extraArgs)
 	} else if day == "2" && part == "1" {
-		result, err = day2.Part1()
+		result, err = day2.Part1(extraArgs)
 	} else if day == "2" && part == "2" {
 		result, err = day2.Part2()
+	} else if day == "2" && part == "report" {
+		result, err = day2.Report(extraArgs)
 	} else if day == "3" && part == "1" {
 		result, err = day3.Part1()
 	} else if day == "3" && part == "2" {
 		result, err = day3.Part2()
+	} else if day == "3" && part == "query" {
+		result, err = day3.Query(extraArgs)
+	} else if day == "3" && part == "render" {
+		result, err = day3.Render(extraArgs)
 	} else if day == "4" && part == "1" {
 		result, err = day4.Part1()
 	} else if day == "4" && part == "2" {
 		result, err = day4.Part2()
+	} else if day == "4" && part == "report" {
+		result, err = day4.Report(extraArgs)
+	} else if day == "4" && part == "validate" {
+		result, err = day4.Validate(extraArgs)
 	} else if day == "5" && part == "1" {
 		result, err = day5.Part1()
 	} else if day == "5" && part == "2" {
 		result, err = day5.Part2()
+	} else if day == "5" && part == "lookup" {
+		result, err = day5.Lookup(extraArgs)
+	} else if day == "5" && part == "reverse" {
+		result, err = day5.Reverse(extraArgs)
+	} else if day == "5" && part == "check" {
+		result, err = day5.Check()
+	} else if day == "5" && part == "compose" {
+		result, err = day5.Compose(extraArgs)
+	} else if day == "5" && part == "validate" {
+		result, err = day5.Validate(extraArgs)
+	} else if day == "5" && part == "ranges" {
+		result, err = day5.Ranges()
 	} else if day == "6" && part == "1" {
 		result, err = day6.Part1()
 	} else if day == "6" && part == "2" {
 		result, err = day6.Part2()
+	} else if day == "6" && part == "table" {
+		result, err = day6.Table(extraArgs)
+	} else if day == "6" && part == "model" {
+		result, err = day6.Model(extraArgs)
+	} else if day == "6" && part == "check" {
+		result, err = day6.Check()
 	} else if day == "7" && part == "1" {
 		result, err = day7.Part1()
 	} else if day == "7" && part == "2" {
@@ -73,13 +171,15 @@ func main() {
 	} else if day == "10" && part == "1" {
 		result, err = day10.Part1()
 	} else if day == "10" && part == "2" {
-		result, err = day10.Part2()
+		result, err = day10.Part2(extraArgs)
 	} else if day == "11" && part == "1" {
 		result, err = day11.Part1()
 	} else if day == "11" && part == "2" {
 		result, err = day11.Part2()
 	} else if day == "12" && part == "1" {
 		result, err = day12.Part1()
+	} else if day == "12" && part == "2" {
+		result, err = day12.Part2()
 	} else {
 		err = fmt.Errorf("Unrecognised day/part: %s/%s", day, part)
 	}
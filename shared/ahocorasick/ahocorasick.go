@@ -0,0 +1,107 @@
+package ahocorasick
+
+// An occurrence of a pattern in the searched text, covering text[Start:End]
+type Match struct {
+	Start   int
+	End     int
+	Pattern int
+}
+
+// Finds all occurrences of a fixed set of patterns in a single pass over the
+// text, including occurrences that overlap each other
+type Matcher struct {
+	patterns []string
+	next     []map[byte]int
+	fail     []int
+	// The patterns that end at each state, including via failure links
+	outputs [][]int
+}
+
+func (m *Matcher) addState() int {
+	m.next = append(m.next, make(map[byte]int))
+	m.fail = append(m.fail, 0)
+	m.outputs = append(m.outputs, nil)
+	return len(m.next) - 1
+}
+
+func New(patterns []string) *Matcher {
+	m := &Matcher{patterns: patterns}
+	m.addState()
+
+	// Build a trie of all the patterns
+	for p, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		state := 0
+		for i := 0; i < len(pattern); i++ {
+			s, ok := m.next[state][pattern[i]]
+			if !ok {
+				s = m.addState()
+				m.next[state][pattern[i]] = s
+			}
+			state = s
+		}
+		m.outputs[state] = append(m.outputs[state], p)
+	}
+
+	// Compute failure links breadth first, so that the failure link of each
+	// state's parent is always known before the state itself
+	queue := []int{}
+	for _, s := range m.next[0] {
+		queue = append(queue, s)
+	}
+	for i := 0; i < len(queue); i++ {
+		state := queue[i]
+		for c, s := range m.next[state] {
+			f := m.fail[state]
+			for {
+				if t, ok := m.next[f][c]; ok {
+					m.fail[s] = t
+					break
+				}
+				if f == 0 {
+					break
+				}
+				f = m.fail[f]
+			}
+			m.outputs[s] = append(m.outputs[s], m.outputs[m.fail[s]]...)
+			queue = append(queue, s)
+		}
+	}
+
+	return m
+}
+
+func (m *Matcher) Patterns() []string {
+	return m.patterns
+}
+
+// Calls fn for each match in the text, in order of end position
+func (m *Matcher) Scan(text string, fn func(Match)) {
+	state := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		for {
+			if s, ok := m.next[state][c]; ok {
+				state = s
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = m.fail[state]
+		}
+		for _, p := range m.outputs[state] {
+			fn(Match{i + 1 - len(m.patterns[p]), i + 1, p})
+		}
+	}
+}
+
+func (m *Matcher) FindAll(text string) []Match {
+	matches := []Match{}
+	m.Scan(text, func(match Match) {
+		matches = append(matches, match)
+	})
+	return matches
+}
@@ -0,0 +1,62 @@
+package containers
+
+import "math/bits"
+
+// A set of non-negative integers stored as a bit per value. The set grows
+// as larger values are added. Negative values are never in the set, so
+// Set and Unset ignore them.
+type BitSet struct {
+	words []uint64
+}
+
+func NewBitSet(capacity int) *BitSet {
+	return &BitSet{make([]uint64, (capacity+63)/64)}
+}
+
+func (b *BitSet) Set(i int) {
+	if i < 0 {
+		return
+	}
+	w := i / 64
+	for w >= len(b.words) {
+		b.words = append(b.words, 0)
+	}
+	b.words[w] |= 1 << (i % 64)
+}
+
+func (b *BitSet) Unset(i int) {
+	w := i / 64
+	if i >= 0 && w < len(b.words) {
+		b.words[w] &^= 1 << (i % 64)
+	}
+}
+
+func (b *BitSet) Has(i int) bool {
+	w := i / 64
+	return i >= 0 && w < len(b.words) && b.words[w]&(1<<(i%64)) != 0
+}
+
+// Returns the number of values in the set
+func (b *BitSet) Count() int {
+	n := 0
+	for _, w := range b.words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Returns the number of values in both sets
+func (b *BitSet) IntersectionCount(other *BitSet) int {
+	n := 0
+	for i := 0; i < min(len(b.words), len(other.words)); i++ {
+		n += bits.OnesCount64(b.words[i] & other.words[i])
+	}
+	return n
+}
+
+// Removes all values from the set
+func (b *BitSet) Clear() {
+	for i := range b.words {
+		b.words[i] = 0
+	}
+}
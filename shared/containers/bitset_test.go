@@ -0,0 +1,122 @@
+package containers
+
+import "testing"
+
+func TestBitSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  int
+		set       []int
+		unset     []int
+		wantHas   []int
+		wantNot   []int
+		wantCount int
+	}{
+		{
+			name:      "empty",
+			capacity:  10,
+			wantNot:   []int{0, 9, 10, 1000},
+			wantCount: 0,
+		},
+		{
+			name:      "word boundaries",
+			capacity:  128,
+			set:       []int{0, 63, 64, 127},
+			wantHas:   []int{0, 63, 64, 127},
+			wantNot:   []int{1, 62, 65, 128},
+			wantCount: 4,
+		},
+		{
+			name:      "grows past capacity",
+			capacity:  1,
+			set:       []int{5, 200},
+			wantHas:   []int{5, 200},
+			wantNot:   []int{199, 201},
+			wantCount: 2,
+		},
+		{
+			name:      "unset",
+			capacity:  64,
+			set:       []int{1, 2, 3},
+			unset:     []int{2, 1000},
+			wantHas:   []int{1, 3},
+			wantNot:   []int{2},
+			wantCount: 2,
+		},
+		{
+			name:      "setting twice",
+			capacity:  64,
+			set:       []int{7, 7},
+			wantHas:   []int{7},
+			wantCount: 1,
+		},
+		{
+			name:      "negative values are ignored",
+			capacity:  64,
+			set:       []int{-1, -64, 3},
+			unset:     []int{-1},
+			wantHas:   []int{3},
+			wantNot:   []int{-1, -64},
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBitSet(tt.capacity)
+			for _, i := range tt.set {
+				b.Set(i)
+			}
+			for _, i := range tt.unset {
+				b.Unset(i)
+			}
+			for _, i := range tt.wantHas {
+				if !b.Has(i) {
+					t.Errorf("Has(%d) = false, want true", i)
+				}
+			}
+			for _, i := range tt.wantNot {
+				if b.Has(i) {
+					t.Errorf("Has(%d) = true, want false", i)
+				}
+			}
+			if got := b.Count(); got != tt.wantCount {
+				t.Errorf("Count() = %d, want %d", got, tt.wantCount)
+			}
+
+			b.Clear()
+			if got := b.Count(); got != 0 {
+				t.Errorf("Count() after Clear() = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestBitSetIntersectionCount(t *testing.T) {
+	tests := []struct {
+		a    []int
+		b    []int
+		want int
+	}{
+		{[]int{}, []int{}, 0},
+		{[]int{1, 2, 3}, []int{2, 3, 4}, 2},
+		{[]int{1, 100}, []int{100}, 1},
+		{[]int{1000}, []int{1}, 0},
+	}
+
+	for _, tt := range tests {
+		a, b := NewBitSet(0), NewBitSet(0)
+		for _, i := range tt.a {
+			a.Set(i)
+		}
+		for _, i := range tt.b {
+			b.Set(i)
+		}
+		if got := a.IntersectionCount(b); got != tt.want {
+			t.Errorf("%v.IntersectionCount(%v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := b.IntersectionCount(a); got != tt.want {
+			t.Errorf("%v.IntersectionCount(%v) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
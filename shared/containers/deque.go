@@ -0,0 +1,95 @@
+package containers
+
+// A double-ended queue backed by a ring buffer that grows as needed
+type Deque[T any] struct {
+	items []T
+	head  int
+	size  int
+}
+
+func NewDeque[T any]() *Deque[T] {
+	return &Deque[T]{}
+}
+
+func (d *Deque[T]) Len() int {
+	return d.size
+}
+
+func (d *Deque[T]) grow() {
+	newCap := 2 * len(d.items)
+	if newCap == 0 {
+		newCap = 8
+	}
+	items := make([]T, newCap)
+	for i := 0; i < d.size; i++ {
+		items[i] = d.items[(d.head+i)%len(d.items)]
+	}
+	d.items = items
+	d.head = 0
+}
+
+func (d *Deque[T]) PushBack(value T) {
+	if d.size == len(d.items) {
+		d.grow()
+	}
+	d.items[(d.head+d.size)%len(d.items)] = value
+	d.size++
+}
+
+func (d *Deque[T]) PushFront(value T) {
+	if d.size == len(d.items) {
+		d.grow()
+	}
+	d.head = (d.head - 1 + len(d.items)) % len(d.items)
+	d.items[d.head] = value
+	d.size++
+}
+
+func (d *Deque[T]) PopFront() (T, bool) {
+	var zero T
+	if d.size == 0 {
+		return zero, false
+	}
+	value := d.items[d.head]
+	// Clear the slot so the value can be garbage collected
+	d.items[d.head] = zero
+	d.head = (d.head + 1) % len(d.items)
+	d.size--
+	return value, true
+}
+
+func (d *Deque[T]) PopBack() (T, bool) {
+	var zero T
+	if d.size == 0 {
+		return zero, false
+	}
+	i := (d.head + d.size - 1) % len(d.items)
+	value := d.items[i]
+	d.items[i] = zero
+	d.size--
+	return value, true
+}
+
+func (d *Deque[T]) Front() (T, bool) {
+	if d.size == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.items[d.head], true
+}
+
+func (d *Deque[T]) Back() (T, bool) {
+	if d.size == 0 {
+		var zero T
+		return zero, false
+	}
+	return d.items[(d.head+d.size-1)%len(d.items)], true
+}
+
+// Returns the i'th value from the front of the deque
+func (d *Deque[T]) At(i int) T {
+	if i < 0 || i >= d.size {
+		panic("deque index out of range")
+	}
+	return d.items[(d.head+i)%len(d.items)]
+}
@@ -0,0 +1,145 @@
+package containers
+
+import "testing"
+
+// Checks that the deque holds exactly the given values, front to back
+func checkDeque(t *testing.T, d *Deque[int], want []int) {
+	t.Helper()
+	if d.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", d.Len(), len(want))
+	}
+	for i, w := range want {
+		if got := d.At(i); got != w {
+			t.Fatalf("At(%d) = %d, want %d", i, got, w)
+		}
+	}
+}
+
+func TestDeque(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(d *Deque[int])
+		want []int
+	}{
+		{
+			name: "push back then pop front is a queue",
+			ops: func(d *Deque[int]) {
+				for i := 0; i < 5; i++ {
+					d.PushBack(i)
+				}
+				d.PopFront()
+				d.PopFront()
+			},
+			want: []int{2, 3, 4},
+		},
+		{
+			name: "push front then pop back is a queue",
+			ops: func(d *Deque[int]) {
+				for i := 0; i < 5; i++ {
+					d.PushFront(i)
+				}
+				d.PopBack()
+			},
+			want: []int{4, 3, 2, 1},
+		},
+		{
+			name: "push back then pop back is a stack",
+			ops: func(d *Deque[int]) {
+				for i := 0; i < 4; i++ {
+					d.PushBack(i)
+				}
+				d.PopBack()
+			},
+			want: []int{0, 1, 2},
+		},
+		{
+			name: "grows while wrapped around",
+			ops: func(d *Deque[int]) {
+				// Fill the initial buffer of 8, then move the head part way round
+				for i := 0; i < 8; i++ {
+					d.PushBack(i)
+				}
+				for i := 0; i < 5; i++ {
+					d.PopFront()
+				}
+				for i := 8; i < 13; i++ {
+					d.PushBack(i)
+				}
+				// The buffer is full and wrapped, so this has to grow it
+				d.PushBack(13)
+				d.PushFront(4)
+			},
+			want: []int{4, 5, 6, 7, 8, 9, 10, 11, 12, 13},
+		},
+		{
+			name: "grows when pushing to the front",
+			ops: func(d *Deque[int]) {
+				for i := 0; i < 20; i++ {
+					d.PushFront(i)
+				}
+			},
+			want: []int{19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDeque[int]()
+			tt.ops(d)
+			checkDeque(t, d, tt.want)
+
+			if len(tt.want) > 0 {
+				if front, ok := d.Front(); !ok || front != tt.want[0] {
+					t.Errorf("Front() = %d, %v, want %d, true", front, ok, tt.want[0])
+				}
+				if back, ok := d.Back(); !ok || back != tt.want[len(tt.want)-1] {
+					t.Errorf("Back() = %d, %v, want %d, true", back, ok, tt.want[len(tt.want)-1])
+				}
+			}
+		})
+	}
+}
+
+func TestDequeEmpty(t *testing.T) {
+	d := NewDeque[int]()
+	if _, ok := d.PopFront(); ok {
+		t.Errorf("PopFront() on an empty deque succeeded")
+	}
+	if _, ok := d.PopBack(); ok {
+		t.Errorf("PopBack() on an empty deque succeeded")
+	}
+	if _, ok := d.Front(); ok {
+		t.Errorf("Front() on an empty deque succeeded")
+	}
+
+	d.PushBack(1)
+	d.PopBack()
+	if _, ok := d.Back(); ok {
+		t.Errorf("Back() on an emptied deque succeeded")
+	}
+}
+
+// A breadth first search pushes a few values for each one it pops
+func BenchmarkDequeQueue(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		d := NewDeque[int]()
+		d.PushBack(0)
+		for n := 0; n < 10000; n++ {
+			v, _ := d.PopFront()
+			d.PushBack(v + 1)
+			d.PushBack(v + 2)
+		}
+	}
+}
+
+// The same pattern using a slice, as the days did before
+func BenchmarkSliceQueue(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		queue := []int{0}
+		for n := 0; n < 10000; n++ {
+			v := queue[0]
+			queue = queue[1:]
+			queue = append(queue, v+1, v+2)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package containers
+
+import "container/heap"
+
+type pqItem[T comparable] struct {
+	value    T
+	priority int
+}
+
+// Implements heap.Interface and keeps track of where each item is stored
+type pqHeap[T comparable] struct {
+	items   []pqItem[T]
+	indexes map[T]int
+}
+
+func (h *pqHeap[T]) Len() int {
+	return len(h.items)
+}
+
+func (h *pqHeap[T]) Less(i, j int) bool {
+	return h.items[i].priority < h.items[j].priority
+}
+
+func (h *pqHeap[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.indexes[h.items[i].value] = i
+	h.indexes[h.items[j].value] = j
+}
+
+func (h *pqHeap[T]) Push(x any) {
+	item := x.(pqItem[T])
+	h.indexes[item.value] = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *pqHeap[T]) Pop() any {
+	last := len(h.items) - 1
+	item := h.items[last]
+	h.items = h.items[:last]
+	delete(h.indexes, item.value)
+	return item
+}
+
+// A min-priority queue backed by a binary heap. Each value can be in the
+// queue at most once, which allows its priority to be changed in place.
+type PriorityQueue[T comparable] struct {
+	h pqHeap[T]
+}
+
+func NewPriorityQueue[T comparable]() *PriorityQueue[T] {
+	return &PriorityQueue[T]{pqHeap[T]{indexes: make(map[T]int)}}
+}
+
+func (pq *PriorityQueue[T]) Len() int {
+	return pq.h.Len()
+}
+
+func (pq *PriorityQueue[T]) Contains(value T) bool {
+	_, ok := pq.h.indexes[value]
+	return ok
+}
+
+// Returns the priority of a value in the queue
+func (pq *PriorityQueue[T]) Priority(value T) (int, bool) {
+	i, ok := pq.h.indexes[value]
+	if !ok {
+		return 0, false
+	}
+	return pq.h.items[i].priority, true
+}
+
+// Adds a value to the queue, or changes its priority if it is already present
+func (pq *PriorityQueue[T]) Push(value T, priority int) {
+	if i, ok := pq.h.indexes[value]; ok {
+		pq.h.items[i].priority = priority
+		heap.Fix(&pq.h, i)
+		return
+	}
+	heap.Push(&pq.h, pqItem[T]{value, priority})
+}
+
+// Lowers the priority of a value already in the queue. Returns false if the
+// value is not present or the new priority is not lower than the current one.
+func (pq *PriorityQueue[T]) DecreaseKey(value T, priority int) bool {
+	i, ok := pq.h.indexes[value]
+	if !ok || priority >= pq.h.items[i].priority {
+		return false
+	}
+	pq.h.items[i].priority = priority
+	heap.Fix(&pq.h, i)
+	return true
+}
+
+// Returns the value with the lowest priority without removing it
+func (pq *PriorityQueue[T]) Peek() (T, int, bool) {
+	if pq.h.Len() == 0 {
+		var zero T
+		return zero, 0, false
+	}
+	item := pq.h.items[0]
+	return item.value, item.priority, true
+}
+
+// Removes and returns the value with the lowest priority
+func (pq *PriorityQueue[T]) Pop() (T, int, bool) {
+	if pq.h.Len() == 0 {
+		var zero T
+		return zero, 0, false
+	}
+	item := heap.Pop(&pq.h).(pqItem[T])
+	return item.value, item.priority, true
+}
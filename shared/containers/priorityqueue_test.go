@@ -0,0 +1,150 @@
+package containers
+
+import (
+	"sort"
+	"testing"
+)
+
+type pqOp struct {
+	op       string
+	value    string
+	priority int
+}
+
+func TestPriorityQueue(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []pqOp
+		want []string
+	}{
+		{
+			name: "pops in priority order",
+			ops:  []pqOp{{"push", "c", 3}, {"push", "a", 1}, {"push", "d", 4}, {"push", "b", 2}},
+			want: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "decrease key moves a value forward",
+			ops:  []pqOp{{"push", "a", 1}, {"push", "b", 2}, {"push", "c", 3}, {"decrease", "c", 0}},
+			want: []string{"c", "a", "b"},
+		},
+		{
+			name: "decrease key ignores higher priorities",
+			ops:  []pqOp{{"push", "a", 1}, {"push", "b", 2}, {"decrease", "a", 5}},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "re-pushing changes the priority in either direction",
+			ops:  []pqOp{{"push", "a", 1}, {"push", "b", 2}, {"push", "c", 3}, {"push", "a", 4}, {"push", "c", 0}},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "empty queue",
+			ops:  []pqOp{},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pq := NewPriorityQueue[string]()
+			for _, op := range tt.ops {
+				if op.op == "push" {
+					pq.Push(op.value, op.priority)
+				} else {
+					pq.DecreaseKey(op.value, op.priority)
+				}
+			}
+
+			if pq.Len() != len(tt.want) {
+				t.Fatalf("Len() = %d, want %d", pq.Len(), len(tt.want))
+			}
+			got := []string{}
+			lastPriority := -1
+			for pq.Len() > 0 {
+				value, priority, ok := pq.Pop()
+				if !ok {
+					t.Fatalf("Pop() failed with %d values left", pq.Len())
+				}
+				if priority < lastPriority {
+					t.Errorf("popped priority %d after %d", priority, lastPriority)
+				}
+				lastPriority = priority
+				got = append(got, value)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("popped %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("popped %v, want %v", got, tt.want)
+				}
+			}
+			if _, _, ok := pq.Pop(); ok {
+				t.Errorf("Pop() on an empty queue succeeded")
+			}
+		})
+	}
+}
+
+func TestPriorityQueueDecreaseKeyResult(t *testing.T) {
+	pq := NewPriorityQueue[int]()
+	pq.Push(1, 10)
+
+	tests := []struct {
+		value    int
+		priority int
+		want     bool
+	}{
+		{1, 5, true},
+		{1, 5, false},
+		{1, 7, false},
+		{2, 1, false},
+	}
+	for _, tt := range tests {
+		if got := pq.DecreaseKey(tt.value, tt.priority); got != tt.want {
+			t.Errorf("DecreaseKey(%d, %d) = %v, want %v", tt.value, tt.priority, got, tt.want)
+		}
+	}
+	if p, ok := pq.Priority(1); !ok || p != 5 {
+		t.Errorf("Priority(1) = %d, %v, want 5, true", p, ok)
+	}
+}
+
+// The keys pushed by the benchmarks, in a fixed pseudo-random order
+func benchmarkPriorities(n int) []int {
+	priorities := make([]int, n)
+	for i := range priorities {
+		priorities[i] = (i * 7919) % n
+	}
+	return priorities
+}
+
+func BenchmarkPriorityQueue(b *testing.B) {
+	priorities := benchmarkPriorities(1000)
+	for i := 0; i < b.N; i++ {
+		pq := NewPriorityQueue[int]()
+		for v, p := range priorities {
+			pq.Push(v, p)
+		}
+		for pq.Len() > 0 {
+			pq.Pop()
+		}
+	}
+}
+
+// Keeps a slice sorted and pops from the front, as the days did before
+func BenchmarkSortedSliceQueue(b *testing.B) {
+	priorities := benchmarkPriorities(1000)
+	for i := 0; i < b.N; i++ {
+		queue := []int{}
+		for _, p := range priorities {
+			j := sort.SearchInts(queue, p)
+			queue = append(queue, 0)
+			copy(queue[j+1:], queue[j:])
+			queue[j] = p
+		}
+		for len(queue) > 0 {
+			queue = queue[1:]
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package containers
+
+// A disjoint-set union over the elements 0 to n-1, using path compression
+// and union by size
+type UnionFind struct {
+	parents []int
+	sizes   []int
+	count   int
+}
+
+func NewUnionFind(n int) *UnionFind {
+	uf := &UnionFind{
+		parents: make([]int, n),
+		sizes:   make([]int, n),
+		count:   n,
+	}
+	for i := range uf.parents {
+		uf.parents[i] = i
+		uf.sizes[i] = 1
+	}
+	return uf
+}
+
+// Returns the representative element of the set containing x
+func (uf *UnionFind) Find(x int) int {
+	root := x
+	for uf.parents[root] != root {
+		root = uf.parents[root]
+	}
+	for uf.parents[x] != root {
+		x, uf.parents[x] = uf.parents[x], root
+	}
+	return root
+}
+
+// Merges the sets containing x and y. Returns false if they were already the same set.
+func (uf *UnionFind) Union(x int, y int) bool {
+	rx, ry := uf.Find(x), uf.Find(y)
+	if rx == ry {
+		return false
+	}
+	if uf.sizes[rx] < uf.sizes[ry] {
+		rx, ry = ry, rx
+	}
+	uf.parents[ry] = rx
+	uf.sizes[rx] += uf.sizes[ry]
+	uf.count--
+	return true
+}
+
+func (uf *UnionFind) Connected(x int, y int) bool {
+	return uf.Find(x) == uf.Find(y)
+}
+
+// Returns the size of the set containing x
+func (uf *UnionFind) Size(x int) int {
+	return uf.sizes[uf.Find(x)]
+}
+
+// Returns the number of disjoint sets
+func (uf *UnionFind) Count() int {
+	return uf.count
+}
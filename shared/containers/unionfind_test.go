@@ -0,0 +1,87 @@
+package containers
+
+import "testing"
+
+func TestUnionFind(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         int
+		unions    [][2]int
+		wantCount int
+		connected [][2]int
+		separate  [][2]int
+		sizes     map[int]int
+	}{
+		{
+			name:      "no unions",
+			n:         4,
+			wantCount: 4,
+			separate:  [][2]int{{0, 1}, {2, 3}},
+			sizes:     map[int]int{0: 1, 3: 1},
+		},
+		{
+			name:      "chain",
+			n:         5,
+			unions:    [][2]int{{0, 1}, {1, 2}, {2, 3}},
+			wantCount: 2,
+			connected: [][2]int{{0, 3}, {3, 1}},
+			separate:  [][2]int{{0, 4}},
+			sizes:     map[int]int{0: 4, 2: 4, 4: 1},
+		},
+		{
+			name:      "repeated unions",
+			n:         3,
+			unions:    [][2]int{{0, 1}, {1, 0}, {0, 0}},
+			wantCount: 2,
+			connected: [][2]int{{1, 0}},
+			separate:  [][2]int{{1, 2}},
+			sizes:     map[int]int{1: 2},
+		},
+		{
+			name:      "two groups merged",
+			n:         6,
+			unions:    [][2]int{{0, 1}, {2, 3}, {3, 4}, {1, 4}},
+			wantCount: 2,
+			connected: [][2]int{{0, 2}, {1, 3}},
+			separate:  [][2]int{{5, 0}},
+			sizes:     map[int]int{0: 5, 5: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uf := NewUnionFind(tt.n)
+			for _, u := range tt.unions {
+				uf.Union(u[0], u[1])
+			}
+			if got := uf.Count(); got != tt.wantCount {
+				t.Errorf("Count() = %d, want %d", got, tt.wantCount)
+			}
+			for _, c := range tt.connected {
+				if !uf.Connected(c[0], c[1]) {
+					t.Errorf("Connected(%d, %d) = false, want true", c[0], c[1])
+				}
+			}
+			for _, c := range tt.separate {
+				if uf.Connected(c[0], c[1]) {
+					t.Errorf("Connected(%d, %d) = true, want false", c[0], c[1])
+				}
+			}
+			for x, want := range tt.sizes {
+				if got := uf.Size(x); got != want {
+					t.Errorf("Size(%d) = %d, want %d", x, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUnionFindUnionResult(t *testing.T) {
+	uf := NewUnionFind(3)
+	if !uf.Union(0, 1) {
+		t.Errorf("first Union(0, 1) = false, want true")
+	}
+	if uf.Union(1, 0) {
+		t.Errorf("second Union(1, 0) = true, want false")
+	}
+}
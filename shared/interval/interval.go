@@ -0,0 +1,158 @@
+package interval
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A half-open range of integers [Start, End)
+type Interval struct {
+	Start int
+	End   int
+}
+
+func (i Interval) String() string {
+	return fmt.Sprintf("[%d, %d)", i.Start, i.End)
+}
+
+func (i Interval) Length() int {
+	return i.End - i.Start
+}
+
+func (i Interval) IsEmpty() bool {
+	return i.End <= i.Start
+}
+
+func (i Interval) Contains(x int) bool {
+	return x >= i.Start && x < i.End
+}
+
+func (i Interval) Intersect(j Interval) Interval {
+	return Interval{max(i.Start, j.Start), min(i.End, j.End)}
+}
+
+func (i Interval) Overlaps(j Interval) bool {
+	return !i.Intersect(j).IsEmpty()
+}
+
+func (i Interval) Shift(offset int) Interval {
+	return Interval{i.Start + offset, i.End + offset}
+}
+
+// A set of integers stored as sorted, non-overlapping, non-adjacent intervals
+type IntervalSet struct {
+	intervals []Interval
+}
+
+func NewIntervalSet(intervals ...Interval) IntervalSet {
+	sorted := []Interval{}
+	for _, i := range intervals {
+		if !i.IsEmpty() {
+			sorted = append(sorted, i)
+		}
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		return sorted[a].Start < sorted[b].Start
+	})
+
+	merged := []Interval{}
+	for _, i := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && i.Start <= merged[last].End {
+			merged[last].End = max(merged[last].End, i.End)
+		} else {
+			merged = append(merged, i)
+		}
+	}
+	return IntervalSet{merged}
+}
+
+// Returns the intervals making up the set, in ascending order
+func (s IntervalSet) Intervals() []Interval {
+	return append([]Interval{}, s.intervals...)
+}
+
+func (s IntervalSet) IsEmpty() bool {
+	return len(s.intervals) == 0
+}
+
+// Returns the number of integers in the set
+func (s IntervalSet) Size() int {
+	size := 0
+	for _, i := range s.intervals {
+		size += i.Length()
+	}
+	return size
+}
+
+// Returns the smallest value in the set, or false if the set is empty
+func (s IntervalSet) Min() (int, bool) {
+	if len(s.intervals) == 0 {
+		return 0, false
+	}
+	return s.intervals[0].Start, true
+}
+
+// Returns the smallest value in the set that is at least x, or false if there is none
+func (s IntervalSet) Next(x int) (int, bool) {
+	i := sort.Search(len(s.intervals), func(i int) bool {
+		return s.intervals[i].End > x
+	})
+	if i == len(s.intervals) {
+		return 0, false
+	}
+	return max(x, s.intervals[i].Start), true
+}
+
+func (s IntervalSet) Contains(x int) bool {
+	i := sort.Search(len(s.intervals), func(i int) bool {
+		return s.intervals[i].End > x
+	})
+	return i < len(s.intervals) && s.intervals[i].Contains(x)
+}
+
+func (s IntervalSet) Union(t IntervalSet) IntervalSet {
+	return NewIntervalSet(append(s.Intervals(), t.intervals...)...)
+}
+
+func (s IntervalSet) Intersect(t IntervalSet) IntervalSet {
+	result := []Interval{}
+	a, b := 0, 0
+	for a < len(s.intervals) && b < len(t.intervals) {
+		i := s.intervals[a].Intersect(t.intervals[b])
+		if !i.IsEmpty() {
+			result = append(result, i)
+		}
+		if s.intervals[a].End < t.intervals[b].End {
+			a++
+		} else {
+			b++
+		}
+	}
+	return IntervalSet{result}
+}
+
+func (s IntervalSet) Subtract(t IntervalSet) IntervalSet {
+	result := []Interval{}
+	b := 0
+	for _, i := range s.intervals {
+		start := i.Start
+		for b < len(t.intervals) && t.intervals[b].End <= start {
+			b++
+		}
+		for c := b; c < len(t.intervals) && t.intervals[c].Start < i.End; c++ {
+			if t.intervals[c].Start > start {
+				result = append(result, Interval{start, t.intervals[c].Start})
+			}
+			start = max(start, t.intervals[c].End)
+		}
+		if start < i.End {
+			result = append(result, Interval{start, i.End})
+		}
+	}
+	return IntervalSet{result}
+}
+
+func (s IntervalSet) String() string {
+	return fmt.Sprint(s.intervals)
+}
@@ -0,0 +1,141 @@
+package interval
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Maps every value in Source to value + Offset
+type Piece struct {
+	Source Interval
+	Offset int
+}
+
+// A map on the integers that adds a constant offset within each piece and
+// leaves values outside of all pieces unchanged
+type PiecewiseLinearMap struct {
+	pieces []Piece
+}
+
+// Builds a map from the given pieces, which must not overlap
+func NewPiecewiseLinearMap(pieces []Piece) (PiecewiseLinearMap, error) {
+	sorted := []Piece{}
+	for _, p := range pieces {
+		if !p.Source.IsEmpty() {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		return sorted[a].Source.Start < sorted[b].Source.Start
+	})
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].Source.Overlaps(sorted[i].Source) {
+			return PiecewiseLinearMap{}, fmt.Errorf("pieces %v and %v overlap", sorted[i-1].Source, sorted[i].Source)
+		}
+	}
+
+	return PiecewiseLinearMap{simplifyPieces(sorted)}, nil
+}
+
+// Drops identity pieces and merges adjacent pieces with the same offset.
+// The pieces must already be sorted and non-overlapping.
+func simplifyPieces(pieces []Piece) []Piece {
+	result := []Piece{}
+	for _, p := range pieces {
+		if p.Offset == 0 || p.Source.IsEmpty() {
+			continue
+		}
+		last := len(result) - 1
+		if last >= 0 && result[last].Source.End == p.Source.Start && result[last].Offset == p.Offset {
+			result[last].Source.End = p.Source.End
+		} else {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Returns the non-identity pieces of the map, in ascending order of source
+func (m PiecewiseLinearMap) Pieces() []Piece {
+	return append([]Piece{}, m.pieces...)
+}
+
+// Returns the set of values that are not mapped to themselves
+func (m PiecewiseLinearMap) Domain() IntervalSet {
+	intervals := make([]Interval, len(m.pieces))
+	for i, p := range m.pieces {
+		intervals[i] = p.Source
+	}
+	return NewIntervalSet(intervals...)
+}
+
+func (m PiecewiseLinearMap) Lookup(x int) int {
+	i := sort.Search(len(m.pieces), func(i int) bool {
+		return m.pieces[i].Source.End > x
+	})
+	if i < len(m.pieces) && m.pieces[i].Source.Contains(x) {
+		return x + m.pieces[i].Offset
+	}
+	return x
+}
+
+// Splits an interval at the boundaries of the map's pieces and returns
+// each part along with the offset that applies to it
+func (m PiecewiseLinearMap) Split(in Interval) []Piece {
+	parts := []Piece{}
+	start := in.Start
+	i := sort.Search(len(m.pieces), func(i int) bool {
+		return m.pieces[i].Source.End > start
+	})
+	for ; i < len(m.pieces) && m.pieces[i].Source.Start < in.End; i++ {
+		p := m.pieces[i]
+		if p.Source.Start > start {
+			parts = append(parts, Piece{Interval{start, p.Source.Start}, 0})
+			start = p.Source.Start
+		}
+		end := min(p.Source.End, in.End)
+		parts = append(parts, Piece{Interval{start, end}, p.Offset})
+		start = end
+	}
+	if start < in.End {
+		parts = append(parts, Piece{Interval{start, in.End}, 0})
+	}
+	return parts
+}
+
+// Returns the image of every value in the set
+func (m PiecewiseLinearMap) MapSet(s IntervalSet) IntervalSet {
+	result := []Interval{}
+	for _, in := range s.intervals {
+		for _, part := range m.Split(in) {
+			result = append(result, part.Source.Shift(part.Offset))
+		}
+	}
+	return NewIntervalSet(result...)
+}
+
+// Returns the map equivalent to applying m and then next
+func (m PiecewiseLinearMap) Compose(next PiecewiseLinearMap) PiecewiseLinearMap {
+	pieces := []Piece{}
+
+	// Values moved by m may then be moved again by next
+	for _, p := range m.pieces {
+		for _, part := range next.Split(p.Source.Shift(p.Offset)) {
+			pieces = append(pieces, Piece{part.Source.Shift(-p.Offset), p.Offset + part.Offset})
+		}
+	}
+
+	// Values left alone by m are only affected by next
+	untouched := next.Domain().Subtract(m.Domain())
+	for _, in := range untouched.intervals {
+		for _, part := range next.Split(in) {
+			pieces = append(pieces, part)
+		}
+	}
+
+	sort.Slice(pieces, func(a, b int) bool {
+		return pieces[a].Source.Start < pieces[b].Source.Start
+	})
+	return PiecewiseLinearMap{simplifyPieces(pieces)}
+}
@@ -0,0 +1,111 @@
+package interval
+
+import (
+	"math"
+	"sort"
+)
+
+// Every representable integer, apart from math.MaxInt itself
+var allIntegers = Interval{math.MinInt, math.MaxInt}
+
+// Like PiecewiseLinearMap but the pieces may overlap, so a value can map to
+// several values or to none at all. This is what you get when inverting a
+// map that is not one to one.
+type Relation struct {
+	pieces []Piece
+}
+
+// Builds a relation from the given pieces, merging pieces with the same offset
+func NewRelation(pieces []Piece) Relation {
+	byOffset := map[int][]Interval{}
+	for _, p := range pieces {
+		byOffset[p.Offset] = append(byOffset[p.Offset], p.Source)
+	}
+
+	merged := []Piece{}
+	for offset, sources := range byOffset {
+		for _, in := range NewIntervalSet(sources...).intervals {
+			merged = append(merged, Piece{in, offset})
+		}
+	}
+	sort.Slice(merged, func(a, b int) bool {
+		if merged[a].Source.Start != merged[b].Source.Start {
+			return merged[a].Source.Start < merged[b].Source.Start
+		}
+		return merged[a].Offset < merged[b].Offset
+	})
+	return Relation{merged}
+}
+
+// Returns the relation that maps every value to itself
+func IdentityRelation() Relation {
+	return Relation{[]Piece{{allIntegers, 0}}}
+}
+
+// Returns the pieces of the relation, ordered by source
+func (r Relation) Pieces() []Piece {
+	return append([]Piece{}, r.pieces...)
+}
+
+// Returns the relation that takes each value back to the values that map to it.
+// Values outside of every piece map to themselves, so they are their own preimage.
+func (m PiecewiseLinearMap) Inverse() Relation {
+	pieces := []Piece{}
+	for _, p := range m.pieces {
+		pieces = append(pieces, Piece{p.Source.Shift(p.Offset), -p.Offset})
+	}
+	for _, in := range NewIntervalSet(allIntegers).Subtract(m.Domain()).intervals {
+		pieces = append(pieces, Piece{in, 0})
+	}
+	return NewRelation(pieces)
+}
+
+// Returns the values that x maps to, and how many values after x map in the
+// same way i.e. if x maps to y then x + 1 maps to y + 1
+func (r Relation) Lookup(x int) ([]int, int) {
+	values := []int{}
+	following := valuesBetween(x, math.MaxInt)
+	for _, p := range r.pieces {
+		if p.Source.Contains(x) {
+			values = append(values, x+p.Offset)
+			following = min(following, valuesBetween(x, p.Source.End))
+		} else if p.Source.Start > x {
+			following = min(following, valuesBetween(x, p.Source.Start))
+		}
+	}
+	return values, following
+}
+
+// Returns how many values lie strictly between x and y, where x < y,
+// saturating at math.MaxInt
+func valuesBetween(x int, y int) int {
+	if x < 0 && y > math.MaxInt+x {
+		return math.MaxInt
+	}
+	return y - x - 1
+}
+
+// Returns every value that some value in the set maps to
+func (r Relation) Image(s IntervalSet) IntervalSet {
+	result := []Interval{}
+	for _, p := range r.pieces {
+		for _, in := range s.intervals {
+			result = append(result, p.Source.Intersect(in).Shift(p.Offset))
+		}
+	}
+	return NewIntervalSet(result...)
+}
+
+// Returns the relation equivalent to applying r and then next
+func (r Relation) Compose(next Relation) Relation {
+	pieces := []Piece{}
+	for _, p := range r.pieces {
+		for _, q := range next.pieces {
+			overlap := p.Source.Shift(p.Offset).Intersect(q.Source)
+			if !overlap.IsEmpty() {
+				pieces = append(pieces, Piece{overlap.Shift(-p.Offset), p.Offset + q.Offset})
+			}
+		}
+	}
+	return NewRelation(pieces)
+}
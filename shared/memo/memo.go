@@ -0,0 +1,138 @@
+package memo
+
+import (
+	"container/list"
+	"fmt"
+)
+
+type Stats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+	Size      int
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d hits, %d misses, %d evictions, %d entries", s.Hits, s.Misses, s.Evictions, s.Size)
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Caches the results of a function that may call itself recursively.
+// The function is given a recurse callback that it should use instead of
+// calling itself directly, so that the recursive calls are also cached.
+type Memo[K comparable, V any] struct {
+	fn      func(recurse func(K) V, key K) V
+	cache   map[K]*list.Element
+	order   *list.List
+	maxSize int
+	stats   Stats
+}
+
+// Creates an unbounded memo for the given function
+func New[K comparable, V any](fn func(recurse func(K) V, key K) V) *Memo[K, V] {
+	return NewBounded(0, fn)
+}
+
+// Creates a memo that holds at most maxSize results, evicting the least
+// recently used result when full. A maxSize of zero means no limit.
+func NewBounded[K comparable, V any](maxSize int, fn func(recurse func(K) V, key K) V) *Memo[K, V] {
+	return &Memo[K, V]{
+		fn:      fn,
+		cache:   make(map[K]*list.Element),
+		order:   list.New(),
+		maxSize: maxSize,
+	}
+}
+
+func (m *Memo[K, V]) Call(key K) V {
+	if e, ok := m.cache[key]; ok {
+		m.stats.Hits++
+		m.order.MoveToFront(e)
+		return e.Value.(entry[K, V]).value
+	}
+
+	m.stats.Misses++
+	value := m.fn(m.Call, key)
+
+	// The recursive calls may have already stored this key
+	if e, ok := m.cache[key]; ok {
+		m.order.MoveToFront(e)
+		return value
+	}
+
+	m.cache[key] = m.order.PushFront(entry[K, V]{key, value})
+	if m.maxSize > 0 && m.order.Len() > m.maxSize {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.cache, oldest.Value.(entry[K, V]).key)
+		m.stats.Evictions++
+	}
+
+	return value
+}
+
+func (m *Memo[K, V]) Stats() Stats {
+	s := m.stats
+	s.Size = m.order.Len()
+	return s
+}
+
+// Removes all cached results and resets the stats
+func (m *Memo[K, V]) Clear() {
+	m.cache = make(map[K]*list.Element)
+	m.order.Init()
+	m.stats = Stats{}
+}
+
+// Like Memo but for functions whose argument is not comparable, or where
+// only part of the argument determines the result. The key function maps
+// each argument to the value used for caching.
+type KeyedMemo[A any, K comparable, V any] struct {
+	key  func(A) K
+	fn   func(recurse func(A) V, arg A) V
+	memo *Memo[K, V]
+	arg  A
+}
+
+func NewKeyed[A any, K comparable, V any](key func(A) K, fn func(recurse func(A) V, arg A) V) *KeyedMemo[A, K, V] {
+	return NewKeyedBounded(0, key, fn)
+}
+
+func NewKeyedBounded[A any, K comparable, V any](maxSize int, key func(A) K, fn func(recurse func(A) V, arg A) V) *KeyedMemo[A, K, V] {
+	km := &KeyedMemo[A, K, V]{key: key, fn: fn}
+	km.memo = NewBounded(maxSize, func(_ func(K) V, _ K) V {
+		// The memo only calls this on a miss for the argument currently being evaluated
+		return km.fn(km.Call, km.arg)
+	})
+	return km
+}
+
+func (km *KeyedMemo[A, K, V]) Call(arg A) V {
+	prev := km.arg
+	km.arg = arg
+	v := km.memo.Call(km.key(arg))
+	km.arg = prev
+	return v
+}
+
+func (km *KeyedMemo[A, K, V]) Stats() Stats {
+	return km.memo.Stats()
+}
+
+func (km *KeyedMemo[A, K, V]) Clear() {
+	km.memo.Clear()
+}
+
+// Creates a memo for a function taking a slice, keyed on the slice contents
+func NewSlice[E any, V any](fn func(recurse func([]E) V, xs []E) V) *KeyedMemo[[]E, string, V] {
+	return NewKeyed(SliceKey[E], fn)
+}
+
+// Returns a string that uniquely identifies the contents of a slice
+func SliceKey[E any](xs []E) string {
+	return fmt.Sprintf("%#v", xs)
+}
@@ -0,0 +1,270 @@
+package numtheory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Returns the non-negative greatest common divisor of a and b
+func GCD(a int, b int) int {
+	a, b = abs(a), abs(b)
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Returns the lowest common multiple of all the given values, or an error
+// if the result does not fit in an int. The LCM of an empty slice is 1.
+func LCM(xs []int) (int, error) {
+	result := 1
+	for _, x := range xs {
+		if x == 0 {
+			return 0, nil
+		}
+		x = abs(x)
+		r, ok := MulChecked(result/GCD(result, x), x)
+		if !ok {
+			return 0, fmt.Errorf("LCM of %v overflows int", xs)
+		}
+		result = r
+	}
+	return result, nil
+}
+
+// Same as LCM but computed using math/big so it cannot overflow
+func BigLCM(xs []int) *big.Int {
+	result := big.NewInt(1)
+	g := new(big.Int)
+	for _, x := range xs {
+		if x == 0 {
+			return big.NewInt(0)
+		}
+		bx := big.NewInt(int64(abs(x)))
+		g.GCD(nil, nil, result, bx)
+		result.Div(result, g)
+		result.Mul(result, bx)
+	}
+	return result
+}
+
+// Returns g, x, y such that a*x + b*y = g = gcd(a, b)
+func ExtendedGCD(a int, b int) (int, int, int) {
+	oldR, r := a, b
+	oldS, s := 1, 0
+	oldT, t := 0, 1
+	for r != 0 {
+		q := oldR / r
+		oldR, r = r, oldR-q*r
+		oldS, s = s, oldS-q*s
+		oldT, t = t, oldT-q*t
+	}
+	if oldR < 0 {
+		return -oldR, -oldS, -oldT
+	}
+	return oldR, oldS, oldT
+}
+
+// Returns a + b, and whether the addition happened without overflowing
+func AddChecked(a int, b int) (int, bool) {
+	c := a + b
+	if (b > 0 && c < a) || (b < 0 && c > a) {
+		return 0, false
+	}
+	return c, true
+}
+
+// Returns a - b, and whether the subtraction happened without overflowing
+func SubChecked(a int, b int) (int, bool) {
+	c := a - b
+	if (b > 0 && c > a) || (b < 0 && c < a) {
+		return 0, false
+	}
+	return c, true
+}
+
+// Returns a * b, and whether the multiplication happened without overflowing
+func MulChecked(a int, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	if (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return 0, false
+	}
+	c := a * b
+	if c/b != a {
+		return 0, false
+	}
+	return c, true
+}
+
+// Returns the largest r such that r * r <= n, for non-negative n. The float
+// square root is only used as a first guess and then corrected, as it loses
+// precision once n is above 2^53.
+func ISqrt(n int) int {
+	if n < 0 {
+		panic(fmt.Sprintf("square root of negative number %d", n))
+	}
+	if n < 2 {
+		return n
+	}
+
+	r := int(math.Sqrt(float64(n)))
+	for r > n/r {
+		r--
+	}
+	for r+1 <= n/(r+1) {
+		r++
+	}
+	return r
+}
+
+// Returns x mod m, always in the range [0, m)
+func Mod(x int, m int) int {
+	r := x % m
+	if r < 0 {
+		r += m
+	}
+	return r
+}
+
+// Returns (a * b) mod m, falling back to math/big if the product overflows
+func MulMod(a int, b int, m int) int {
+	a, b = Mod(a, m), Mod(b, m)
+	if c, ok := MulChecked(a, b); ok {
+		return c % m
+	}
+	c := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(b)))
+	return int(c.Mod(c, big.NewInt(int64(m))).Int64())
+}
+
+// Returns (base ^ exp) mod m for a non-negative exponent and positive modulus
+func ModPow(base int, exp int, m int) int {
+	if m == 1 {
+		return 0
+	}
+	result := 1
+	base = Mod(base, m)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result = MulMod(result, base, m)
+		}
+		base = MulMod(base, base, m)
+		exp >>= 1
+	}
+	return result
+}
+
+// Returned by CRT when the congruences have no common solution
+var ErrNoSolution = errors.New("congruences have no common solution")
+
+// Solves the system x = residues[i] (mod moduli[i]) for all i, where the
+// moduli need not be pairwise coprime. Returns the smallest non-negative
+// solution and the modulus of the combined congruence (the LCM of the
+// moduli). The error is ErrNoSolution if there is no solution, or some other
+// error if the solution or modulus does not fit in an int, in which case
+// CRTBig can be used instead.
+func CRT(residues []int, moduli []int) (int, int, error) {
+	if len(residues) != len(moduli) {
+		return 0, 0, fmt.Errorf("%d residues but %d moduli", len(residues), len(moduli))
+	}
+	for _, m := range moduli {
+		if m <= 0 {
+			return 0, 0, fmt.Errorf("modulus %d is not positive", m)
+		}
+	}
+
+	x, m := 0, 1
+	for i := range residues {
+		var ok bool
+		x, m, ok = combineCongruences(x, m, residues[i], moduli[i])
+		if !ok {
+			// Either there is no solution, or the arithmetic overflowed
+			bx, bm, ok := CRTBig(residues, moduli)
+			if !ok {
+				return 0, 0, ErrNoSolution
+			}
+			if !bx.IsInt64() || !bm.IsInt64() {
+				return 0, 0, fmt.Errorf("solution %v (mod %v) overflows int", bx, bm)
+			}
+			return int(bx.Int64()), int(bm.Int64()), nil
+		}
+	}
+	return x, m, nil
+}
+
+// Merges x = a1 (mod m1) and x = a2 (mod m2) into a single congruence.
+// Returns ok as false if there is no solution or if the computation overflows.
+func combineCongruences(a1 int, m1 int, a2 int, m2 int) (int, int, bool) {
+	if m1 <= 0 || m2 <= 0 {
+		return 0, 0, false
+	}
+	a1, a2 = Mod(a1, m1), Mod(a2, m2)
+
+	g, p, _ := ExtendedGCD(m1, m2)
+	diff, ok := SubChecked(a2, a1)
+	if !ok || diff%g != 0 {
+		return 0, 0, false
+	}
+
+	m2g := m2 / g
+	lcm, ok := MulChecked(m1, m2g)
+	if !ok {
+		return 0, 0, false
+	}
+
+	// x = a1 + m1 * (diff / g * p mod m2/g)
+	k := MulMod(diff/g, p, m2g)
+	step, ok := MulChecked(m1, k)
+	if !ok {
+		return 0, 0, false
+	}
+	x, ok := AddChecked(a1, step)
+	if !ok {
+		return 0, 0, false
+	}
+	return Mod(x, lcm), lcm, true
+}
+
+// Same as CRT but computed using math/big so it cannot overflow
+func CRTBig(residues []int, moduli []int) (*big.Int, *big.Int, bool) {
+	if len(residues) != len(moduli) {
+		return nil, nil, false
+	}
+
+	x, m := big.NewInt(0), big.NewInt(1)
+	for i := range residues {
+		if moduli[i] <= 0 {
+			return nil, nil, false
+		}
+		m2 := big.NewInt(int64(moduli[i]))
+		a2 := new(big.Int).Mod(big.NewInt(int64(residues[i])), m2)
+
+		g, p := new(big.Int), new(big.Int)
+		g.GCD(p, nil, m, m2)
+
+		diff := new(big.Int).Sub(a2, x)
+		q, r := new(big.Int).QuoRem(diff, g, new(big.Int))
+		if r.Sign() != 0 {
+			return nil, nil, false
+		}
+
+		m2g := new(big.Int).Div(m2, g)
+		k := q.Mul(q, p)
+		k.Mod(k, m2g)
+
+		x.Add(x, k.Mul(k, m))
+		m.Mul(m, m2g)
+		x.Mod(x, m)
+	}
+	return x, m, true
+}
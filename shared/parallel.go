@@ -0,0 +1,143 @@
+package shared
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// An error that occurred while processing a particular line, numbered from 1
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Returns how many chunks [0, n) is split into and how big each one is
+func chunkLayout(n int) (int, int) {
+	// Use more chunks than workers so that a slow chunk doesn't hold everyone up
+	numChunks := min(n, runtime.GOMAXPROCS(0)*4)
+	if numChunks == 0 {
+		return 0, 0
+	}
+	chunkSize := (n + numChunks - 1) / numChunks
+	return (n + chunkSize - 1) / chunkSize, chunkSize
+}
+
+// Splits the indexes [0, n) into chunks and calls process on each chunk
+// across GOMAXPROCS workers. process is given the index of the chunk and
+// the range of indexes it covers, and a shouldStop function that reports
+// whether an earlier index has already failed. Returns the error from the
+// earliest failing index.
+func forEachChunk(n int, process func(chunk int, start int, end int, shouldStop func(i int) bool) *LineError) error {
+	numChunks, chunkSize := chunkLayout(n)
+
+	errs := make([]*LineError, numChunks)
+
+	// Indexes after one that has already failed don't need processing
+	var firstErrorIndex atomic.Int64
+	firstErrorIndex.Store(int64(n))
+	shouldStop := func(i int) bool {
+		return int64(i) > firstErrorIndex.Load()
+	}
+
+	var nextChunk atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.GOMAXPROCS(0), numChunks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				c := int(nextChunk.Add(1) - 1)
+				if c >= numChunks {
+					return
+				}
+
+				err := process(c, c*chunkSize, min((c+1)*chunkSize, n), shouldStop)
+				if err != nil {
+					errs[c] = err
+					for {
+						current := firstErrorIndex.Load()
+						if int64(err.Line-1) >= current || firstErrorIndex.CompareAndSwap(current, int64(err.Line-1)) {
+							break
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Processes the lines in parallel chunks. Each line is passed through mapFn
+// and the results are combined using reduceFn, which must be associative.
+// Results are always combined in line order so reduceFn does not need to be
+// commutative. If any line fails then the error from the earliest failing
+// line is returned as a *LineError.
+func ParallelMapReduce[T any](lines []string, zero T, mapFn func(line string) (T, error), reduceFn func(acc T, value T) T) (T, error) {
+	numChunks, _ := chunkLayout(len(lines))
+	results := make([]T, numChunks)
+
+	err := forEachChunk(len(lines), func(chunk int, start int, end int, shouldStop func(i int) bool) *LineError {
+		acc := zero
+		for i := start; i < end && !shouldStop(i); i++ {
+			v, err := mapFn(lines[i])
+			if err != nil {
+				return &LineError{i + 1, err}
+			}
+			acc = reduceFn(acc, v)
+		}
+		results[chunk] = acc
+		return nil
+	})
+	if err != nil {
+		return zero, err
+	}
+
+	result := zero
+	for _, r := range results {
+		result = reduceFn(result, r)
+	}
+	return result, nil
+}
+
+// Sums the result of fn over all lines, processing them in parallel
+func ParallelSum(lines []string, fn func(line string) (int, error)) (int, error) {
+	return ParallelMapReduce(lines, 0, fn, func(acc int, value int) int {
+		return acc + value
+	})
+}
+
+// Applies fn to every line in parallel and returns the results in line order
+func ParallelMap[T any](lines []string, fn func(line string) (T, error)) ([]T, error) {
+	results := make([]T, len(lines))
+	err := forEachChunk(len(lines), func(chunk int, start int, end int, shouldStop func(i int) bool) *LineError {
+		for i := start; i < end && !shouldStop(i); i++ {
+			v, err := fn(lines[i])
+			if err != nil {
+				return &LineError{i + 1, err}
+			}
+			results[i] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
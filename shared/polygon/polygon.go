@@ -0,0 +1,117 @@
+package polygon
+
+import (
+	"robertbrignull/adventofcode2023/shared/numtheory"
+)
+
+type Point struct {
+	X int
+	Y int
+}
+
+type Orientation int
+
+const (
+	Degenerate Orientation = iota
+	Clockwise
+	CounterClockwise
+)
+
+func (o Orientation) String() string {
+	switch o {
+	case Clockwise:
+		return "Clockwise"
+	case CounterClockwise:
+		return "CounterClockwise"
+	default:
+		return "Degenerate"
+	}
+}
+
+// Returns twice the signed area of the polygon using the shoelace formula.
+// The vertices are given in order and the polygon is implicitly closed.
+// The result is positive if the vertices go anticlockwise when the y axis
+// points up, which is clockwise when the y axis points down as in a grid.
+func SignedDoubleArea(vertices []Point) int {
+	a := 0
+	for i := range vertices {
+		p, q := vertices[i], vertices[(i+1)%len(vertices)]
+		a += p.X*q.Y - q.X*p.Y
+	}
+	return a
+}
+
+// Returns twice the area of the polygon, which is always an integer
+func DoubleArea(vertices []Point) int {
+	a := SignedDoubleArea(vertices)
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Returns the orientation of the vertices with the y axis pointing up
+func Winding(vertices []Point) Orientation {
+	a := SignedDoubleArea(vertices)
+	if a > 0 {
+		return CounterClockwise
+	} else if a < 0 {
+		return Clockwise
+	}
+	return Degenerate
+}
+
+// Returns the number of lattice points on the edges of the polygon
+func BoundaryPoints(vertices []Point) int {
+	b := 0
+	for i := range vertices {
+		p, q := vertices[i], vertices[(i+1)%len(vertices)]
+		b += numtheory.GCD(q.X-p.X, q.Y-p.Y)
+	}
+	return b
+}
+
+// Returns the number of lattice points strictly inside the polygon using Pick's theorem:
+// A = I + B/2 - 1  =>  I = (2A - B + 2) / 2
+func InteriorPoints(vertices []Point) int {
+	return (DoubleArea(vertices) - BoundaryPoints(vertices) + 2) / 2
+}
+
+// Returns whether the point lies on one of the polygon's edges
+func OnBoundary(vertices []Point, pt Point) bool {
+	for i := range vertices {
+		p, q := vertices[i], vertices[(i+1)%len(vertices)]
+		cross := (q.X-p.X)*(pt.Y-p.Y) - (q.Y-p.Y)*(pt.X-p.X)
+		if cross == 0 &&
+			pt.X >= min(p.X, q.X) && pt.X <= max(p.X, q.X) &&
+			pt.Y >= min(p.Y, q.Y) && pt.Y <= max(p.Y, q.Y) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns whether the point lies strictly inside the polygon, by casting a ray
+// in the +x direction and counting how many edges it crosses
+func Contains(vertices []Point, pt Point) bool {
+	if OnBoundary(vertices, pt) {
+		return false
+	}
+
+	inside := false
+	for i := range vertices {
+		p, q := vertices[i], vertices[(i+1)%len(vertices)]
+		// Count edges that straddle the ray, treating each vertex as being
+		// slightly above the ray so that vertices on it aren't counted twice
+		if (p.Y > pt.Y) != (q.Y > pt.Y) {
+			// x coordinate of the crossing is p.X + (pt.Y - p.Y) * (q.X - p.X) / (q.Y - p.Y),
+			// compared against pt.X without dividing
+			lhs := (pt.X - p.X) * (q.Y - p.Y)
+			rhs := (pt.Y - p.Y) * (q.X - p.X)
+			if (q.Y > p.Y && lhs < rhs) || (q.Y < p.Y && lhs > rhs) {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
@@ -0,0 +1,61 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (x Severity) String() string {
+	switch x {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return fmt.Sprintf("%d", int(x))
+	}
+}
+
+// A problem found while validating an input file, on a line numbered from 1
+type ValidationIssue struct {
+	Severity Severity
+	Line     int
+	Message  string
+}
+
+func NewIssue(severity Severity, line int, message string) ValidationIssue {
+	return ValidationIssue{severity, line, message}
+}
+
+func (v ValidationIssue) String() string {
+	return fmt.Sprintf("line %d: %s: %s", v.Line, v.Severity, v.Message)
+}
+
+// Lists the issues followed by a count of errors and warnings, where checked
+// describes what was checked e.g. "10 cards". The report is returned as an
+// error if there are any errors, or any warnings when strict is set.
+func ValidationReport(issues []ValidationIssue, checked string, strict bool) (string, error) {
+	var sb strings.Builder
+	numErrors, numWarnings := 0, 0
+	for _, issue := range issues {
+		sb.WriteString(issue.String() + "\n")
+		if issue.Severity == Error || strict {
+			numErrors++
+		} else {
+			numWarnings++
+		}
+	}
+	fmt.Fprintf(&sb, "%s checked, %d errors, %d warnings", checked, numErrors, numWarnings)
+
+	if numErrors > 0 {
+		return "", fmt.Errorf("%s", sb.String())
+	}
+	return sb.String(), nil
+}